@@ -15,6 +15,7 @@
 package authz
 
 import (
+	"context"
 	"fmt"
 
 	apiv1beta "istio.io/api/security/v1beta1"
@@ -30,25 +31,44 @@ func init() {
 
 type allowWithNegativeAuditor struct{}
 
+func (a *allowWithNegativeAuditor) ID() string {
+	return "authz-allow-negative"
+}
+
 func (a *allowWithNegativeAuditor) Name() string {
 	return "Allow with Negative Match"
 }
 
-func (a *allowWithNegativeAuditor) Audit(_ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+func (a *allowWithNegativeAuditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	return policiesWithAction(resources, apiv1beta.AuthorizationPolicy_ALLOW)
+}
+
+func (a *allowWithNegativeAuditor) AuditContext(ctx context.Context, _ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
 	var results []types.AuditResult
 
 	for _, policy := range resources.AuthorizationPolicies {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if policy.Spec.Action != apiv1beta.AuthorizationPolicy_ALLOW {
 			continue
 		}
 
-		offendingRules := evalAllowPolicy(policy)
-
-		if len(offendingRules) > 0 {
+		for _, m := range evalAllowPolicy(policy) {
 			results = append(results, types.AuditResult{
 				Name:        a.Name(),
+				Severity:    types.Medium,
 				Resource:    policy.Namespace + ":" + policy.Name,
+				APIVersion:  security.SchemeGroupVersion.String(),
+				Kind:        "AuthorizationPolicy",
+				Path:        m.path,
+				ID:          m.key,
 				Description: fmt.Sprintf("discovered allow policy with negative matchers in %s", policy.Name),
+				Remediation: "replace the negative matcher with a positive match on the allowed values, " +
+					"so that requests not anticipated by the policy are denied",
+				References: []string{
+					types.BestPracticesURL + "#use-allow-with-positive-matching-and-deny-with-negative-match",
+				},
 			})
 		}
 	}
@@ -56,39 +76,44 @@ func (a *allowWithNegativeAuditor) Audit(_ types.Discovery, resources types.Reso
 	return results, nil
 }
 
-func evalAllowPolicy(policy security.AuthorizationPolicy) []apiv1beta.Rule {
-	var offendingRules []apiv1beta.Rule
+// evalAllowPolicy returns all negative matchers in the policy.
+func evalAllowPolicy(policy security.AuthorizationPolicy) []match {
+	var found []match
 
-	rules := policy.Spec.Rules
-
-	for _, rule := range rules {
-		fromRules := rule.From
-
-		for _, f := range fromRules {
+	for i, rule := range policy.Spec.Rules {
+		for j, f := range rule.From {
 			source := f.Source
+			if source == nil {
+				continue
+			}
 
-			if source.NotIpBlocks != nil ||
-				source.NotNamespaces != nil ||
-				source.NotPrincipals != nil ||
-				source.NotRemoteIpBlocks != nil ||
-				source.NotRequestPrincipals != nil {
-				offendingRules = append(offendingRules, *rule)
+			matchers := []matcher{
+				{"notIpBlocks", source.NotIpBlocks},
+				{"notNamespaces", source.NotNamespaces},
+				{"notPrincipals", source.NotPrincipals},
+				{"notRemoteIpBlocks", source.NotRemoteIpBlocks},
+				{"notRequestPrincipals", source.NotRequestPrincipals},
 			}
+			prefix, section := fmt.Sprintf("spec.rules[%d].from[%d].source", i, j), "from.source"
+			found = append(found, populated(prefix, section, matchers)...)
 		}
 
-		toRules := rule.To
-
-		for _, t := range toRules {
+		for j, t := range rule.To {
 			operation := t.Operation
+			if operation == nil {
+				continue
+			}
 
-			if operation.NotHosts != nil ||
-				operation.NotMethods != nil ||
-				operation.NotPaths != nil ||
-				operation.NotPorts != nil {
-				offendingRules = append(offendingRules, *rule)
+			matchers := []matcher{
+				{"notHosts", operation.NotHosts},
+				{"notMethods", operation.NotMethods},
+				{"notPaths", operation.NotPaths},
+				{"notPorts", operation.NotPorts},
 			}
+			prefix, section := fmt.Sprintf("spec.rules[%d].to[%d].operation", i, j), "to.operation"
+			found = append(found, populated(prefix, section, matchers)...)
 		}
 	}
 
-	return offendingRules
+	return found
 }
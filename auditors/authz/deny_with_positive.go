@@ -15,6 +15,7 @@
 package authz
 
 import (
+	"context"
 	"fmt"
 
 	apiv1beta "istio.io/api/security/v1beta1"
@@ -30,25 +31,44 @@ func init() {
 
 type denyWithPositiveAuditor struct{}
 
+func (a *denyWithPositiveAuditor) ID() string {
+	return "authz-deny-positive"
+}
+
 func (a *denyWithPositiveAuditor) Name() string {
 	return "Deny with Positive Match"
 }
 
-func (a *denyWithPositiveAuditor) Audit(_ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+func (a *denyWithPositiveAuditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	return policiesWithAction(resources, apiv1beta.AuthorizationPolicy_DENY)
+}
+
+func (a *denyWithPositiveAuditor) AuditContext(ctx context.Context, _ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
 	var results []types.AuditResult
 
 	for _, policy := range resources.AuthorizationPolicies {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if policy.Spec.Action != apiv1beta.AuthorizationPolicy_DENY {
 			continue
 		}
 
-		offendingRules := evalDenyPolicy(policy)
-
-		if len(offendingRules) > 0 {
+		for _, m := range evalDenyPolicy(policy) {
 			results = append(results, types.AuditResult{
 				Name:        a.Name(),
+				Severity:    types.Medium,
 				Resource:    policy.Namespace + ":" + policy.Name,
+				APIVersion:  security.SchemeGroupVersion.String(),
+				Kind:        "AuthorizationPolicy",
+				Path:        m.path,
+				ID:          m.key,
 				Description: fmt.Sprintf("discovered deny policy with positive matchers in %s", policy.Name),
+				Remediation: "replace the positive matcher with a negative match on the allowed values, " +
+					"so that requests not anticipated by the policy are denied",
+				References: []string{
+					types.BestPracticesURL + "#use-allow-with-positive-matching-and-deny-with-negative-match",
+				},
 			})
 		}
 	}
@@ -56,39 +76,44 @@ func (a *denyWithPositiveAuditor) Audit(_ types.Discovery, resources types.Resou
 	return results, nil
 }
 
-func evalDenyPolicy(policy security.AuthorizationPolicy) []apiv1beta.Rule {
-	var offendingRules []apiv1beta.Rule
+// evalDenyPolicy returns all positive matchers in the policy.
+func evalDenyPolicy(policy security.AuthorizationPolicy) []match {
+	var found []match
 
-	rules := policy.Spec.Rules
-
-	for _, rule := range rules {
-		fromRules := rule.From
-
-		for _, f := range fromRules {
+	for i, rule := range policy.Spec.Rules {
+		for j, f := range rule.From {
 			source := f.Source
+			if source == nil {
+				continue
+			}
 
-			if source.IpBlocks != nil ||
-				source.Namespaces != nil ||
-				source.Principals != nil ||
-				source.RemoteIpBlocks != nil ||
-				source.RequestPrincipals != nil {
-				offendingRules = append(offendingRules, *rule)
+			matchers := []matcher{
+				{"ipBlocks", source.IpBlocks},
+				{"namespaces", source.Namespaces},
+				{"principals", source.Principals},
+				{"remoteIpBlocks", source.RemoteIpBlocks},
+				{"requestPrincipals", source.RequestPrincipals},
 			}
+			prefix, section := fmt.Sprintf("spec.rules[%d].from[%d].source", i, j), "from.source"
+			found = append(found, populated(prefix, section, matchers)...)
 		}
 
-		toRules := rule.To
-
-		for _, t := range toRules {
+		for j, t := range rule.To {
 			operation := t.Operation
+			if operation == nil {
+				continue
+			}
 
-			if operation.Hosts != nil ||
-				operation.Methods != nil ||
-				operation.Paths != nil ||
-				operation.Ports != nil {
-				offendingRules = append(offendingRules, *rule)
+			matchers := []matcher{
+				{"hosts", operation.Hosts},
+				{"methods", operation.Methods},
+				{"paths", operation.Paths},
+				{"ports", operation.Ports},
 			}
+			prefix, section := fmt.Sprintf("spec.rules[%d].to[%d].operation", i, j), "to.operation"
+			found = append(found, populated(prefix, section, matchers)...)
 		}
 	}
 
-	return offendingRules
+	return found
 }
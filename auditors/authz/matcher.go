@@ -0,0 +1,48 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import "strings"
+
+// matcher is a single source or operation field of an AuthorizationPolicy rule
+// and its values, which are nil if it is not populated.
+type matcher struct {
+	field  string
+	values []string
+}
+
+// match is a populated matcher of a policy.
+type match struct {
+	// path is the path of the matcher within the policy.
+	path string
+	// key names the matcher by its field and values rather than by the
+	// position of its rule, e.g. from.source.notNamespaces=default.
+	key string
+}
+
+// populated returns the populated matchers of the source or operation at
+// prefix, whose field within a rule is section.
+func populated(prefix, section string, matchers []matcher) []match {
+	var found []match
+	for _, m := range matchers {
+		if m.values != nil {
+			found = append(found, match{
+				path: prefix + "." + m.field,
+				key:  section + "." + m.field + "=" + strings.Join(m.values, ","),
+			})
+		}
+	}
+	return found
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	apiv1beta "istio.io/api/security/v1beta1"
+	security "istio.io/client-go/pkg/apis/security/v1beta1"
+)
+
+func testPolicy(action apiv1beta.AuthorizationPolicy_Action) security.AuthorizationPolicy {
+	return security.AuthorizationPolicy{
+		Spec: apiv1beta.AuthorizationPolicy{
+			Action: action,
+			Rules: []*apiv1beta.Rule{
+				{
+					To: []*apiv1beta.Rule_To{
+						{Operation: &apiv1beta.Operation{Paths: []string{"/public"}}},
+					},
+				},
+				{
+					From: []*apiv1beta.Rule_From{
+						{Source: &apiv1beta.Source{Principals: []string{"cluster.local/ns/default/sa/httpbin"}}},
+						{Source: &apiv1beta.Source{NotNamespaces: []string{"default"}}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestEvalAllowPolicy(t *testing.T) {
+	found := evalAllowPolicy(testPolicy(apiv1beta.AuthorizationPolicy_ALLOW))
+	assert.Equal(t, []match{
+		{"spec.rules[1].from[1].source.notNamespaces", "from.source.notNamespaces=default"},
+	}, found)
+}
+
+func TestEvalDenyPolicy(t *testing.T) {
+	found := evalDenyPolicy(testPolicy(apiv1beta.AuthorizationPolicy_DENY))
+	assert.Equal(t, []match{
+		{"spec.rules[0].to[0].operation.paths", "to.operation.paths=/public"},
+		{"spec.rules[1].from[0].source.principals", "from.source.principals=cluster.local/ns/default/sa/httpbin"},
+	}, found)
+}
@@ -0,0 +1,32 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	apiv1beta "istio.io/api/security/v1beta1"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// policiesWithAction returns the AuthorizationPolicies with the given action.
+func policiesWithAction(resources types.Resources, action apiv1beta.AuthorizationPolicy_Action) []string {
+	var scope []string
+	for _, policy := range resources.AuthorizationPolicies {
+		if policy.Spec.Action == action {
+			scope = append(scope, policy.Namespace+":"+policy.Name)
+		}
+	}
+	return scope
+}
@@ -17,9 +17,11 @@
 package destinationrule
 
 import (
+	"context"
 	"fmt"
 
 	networkingv1alpha3 "istio.io/api/networking/v1alpha3"
+	networking "istio.io/client-go/pkg/apis/networking/v1alpha3"
 
 	"github.com/praetorian-inc/snowcat/auditors"
 	"github.com/praetorian-inc/snowcat/pkg/types"
@@ -31,6 +33,10 @@ func init() {
 
 type auditor struct{}
 
+func (a *auditor) ID() string {
+	return "destinationrule-tls-ca-certs"
+}
+
 func (a *auditor) Name() string {
 	return "TLS Validation in Destination Rule"
 }
@@ -40,29 +46,54 @@ func isClientTLSSettingSafe(tls *networkingv1alpha3.ClientTLSSettings) bool {
 	return tls == nil || tls.Mode.String() != "SIMPLE" || tls.CaCertificates != ""
 }
 
-func (a *auditor) Audit(_ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+func (a *auditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	var scope []string
+	for _, rule := range resources.DestinationRules {
+		scope = append(scope, rule.Namespace+":"+rule.Name)
+	}
+	return scope
+}
+
+func (a *auditor) AuditContext(ctx context.Context, _ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
 	var results []types.AuditResult
 
 	for _, rule := range resources.DestinationRules {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if rule.Spec.TrafficPolicy == nil {
 			continue
 		}
 		if !isClientTLSSettingSafe(rule.Spec.TrafficPolicy.Tls) {
-			results = append(results, types.AuditResult{
-				Name:        a.Name(),
-				Resource:    rule.Namespace + ":" + rule.Name,
-				Description: fmt.Sprintf("%s rule missing CA certificates", rule.Name),
-			})
+			results = append(results, a.result(rule, "spec.trafficPolicy.tls", "",
+				fmt.Sprintf("%s rule missing CA certificates", rule.Name)))
 		}
-		for _, policy := range rule.Spec.TrafficPolicy.PortLevelSettings {
+		for i, policy := range rule.Spec.TrafficPolicy.PortLevelSettings {
 			if !isClientTLSSettingSafe(policy.Tls) {
-				results = append(results, types.AuditResult{
-					Name:        a.Name(),
-					Resource:    rule.Namespace + ":" + rule.Name,
-					Description: fmt.Sprintf("%s rule missing CA certificates in traffic policy", rule.Name),
-				})
+				results = append(results, a.result(rule, fmt.Sprintf("spec.trafficPolicy.portLevelSettings[%d].tls", i),
+					fmt.Sprintf("port %d", policy.GetPort().GetNumber()), fmt.Sprintf("%s rule missing CA certificates in traffic policy", rule.Name)))
 			}
 		}
 	}
 	return results, nil
 }
+
+// result reports the TLS settings at path, which are named by id when path
+// indexes the port level settings.
+func (a *auditor) result(rule networking.DestinationRule, path, id, description string) types.AuditResult {
+	return types.AuditResult{
+		Name:        a.Name(),
+		Severity:    types.High,
+		Resource:    rule.Namespace + ":" + rule.Name,
+		APIVersion:  networking.SchemeGroupVersion.String(),
+		Kind:        "DestinationRule",
+		Path:        path,
+		ID:          id,
+		Description: description,
+		Remediation: "set caCertificates to the CA bundle of the destination, or use ISTIO_MUTUAL, " +
+			"so that the server certificate is verified",
+		References: []string{
+			types.BestPracticesURL + "#configure-tls-verification-in-destination-rule-when-using-tls-origination",
+		},
+	}
+}
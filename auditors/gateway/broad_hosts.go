@@ -17,8 +17,11 @@
 package gateway
 
 import (
+	"context"
 	"fmt"
 
+	networking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+
 	"github.com/praetorian-inc/snowcat/auditors"
 	"github.com/praetorian-inc/snowcat/pkg/types"
 )
@@ -29,21 +32,45 @@ func init() {
 
 type auditor struct{}
 
+func (a *auditor) ID() string {
+	return "gateway-broad-hosts"
+}
+
 func (a *auditor) Name() string {
 	return "Overly Broad Gateway Hosts"
 }
 
-func (a *auditor) Audit(_ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+func (a *auditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	var scope []string
+	for _, gateway := range resources.Gateways {
+		scope = append(scope, gateway.Namespace+":"+gateway.Name)
+	}
+	return scope
+}
+
+func (a *auditor) AuditContext(ctx context.Context, _ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
 	var results []types.AuditResult
 
 	for _, gateway := range resources.Gateways {
-		for _, server := range gateway.Spec.Servers {
-			for _, host := range server.Hosts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for i, server := range gateway.Spec.Servers {
+			for j, host := range server.Hosts {
 				if host == "*" {
 					results = append(results, types.AuditResult{
 						Name:        a.Name(),
+						Severity:    types.Low,
 						Resource:    gateway.Namespace + ":" + gateway.Name,
+						APIVersion:  networking.SchemeGroupVersion.String(),
+						Kind:        "Gateway",
+						Path:        fmt.Sprintf("spec.servers[%d].hosts[%d]", i, j),
+						ID:          fmt.Sprintf("port %d host %s", server.GetPort().GetNumber(), host),
 						Description: fmt.Sprintf("%s host gateway is too broad (wildcard host allowed)", gateway.Spec.Selector["istio"]),
+						Remediation: "restrict the server hosts to the domains the gateway is meant to serve",
+						References: []string{
+							types.BestPracticesURL + "#avoid-overly-broad-hosts-configurations",
+						},
 					})
 				}
 			}
@@ -0,0 +1,90 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+package gateway
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	networkingapi "istio.io/api/networking/v1alpha3"
+	networking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func server(port uint32, hosts ...string) *networkingapi.Server {
+	return &networkingapi.Server{
+		Port:  &networkingapi.Port{Number: port, Name: "http", Protocol: "HTTP"},
+		Hosts: hosts,
+	}
+}
+
+func auditServers(t *testing.T, servers ...*networkingapi.Server) []types.AuditResult {
+	resources := types.NewResources()
+	resources.Gateways = []networking.Gateway{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: "istio-system"},
+			Spec: networkingapi.Gateway{
+				Selector: map[string]string{"istio": "ingressgateway"},
+				Servers:  servers,
+			},
+		},
+	}
+	results, err := (&auditor{}).AuditContext(context.Background(), types.Discovery{}, resources)
+	assert.Equal(t, nil, err)
+	return results
+}
+
+func TestBroadHostsFingerprint(t *testing.T) {
+	base := auditServers(t, server(80, "*"))
+	assert.Equal(t, 1, len(base))
+	assert.Equal(t, "spec.servers[0].hosts[0]", base[0].Path)
+
+	type testcase struct {
+		description string
+		servers     []*networkingapi.Server
+		path        string
+		same        bool
+	}
+	testcases := []testcase{
+		{
+			description: "server inserted before",
+			servers:     []*networkingapi.Server{server(443, "example.com"), server(80, "*")},
+			path:        "spec.servers[1].hosts[0]",
+			same:        true,
+		},
+		{
+			description: "host inserted before",
+			servers:     []*networkingapi.Server{server(80, "example.com", "*")},
+			path:        "spec.servers[0].hosts[1]",
+			same:        true,
+		},
+		{
+			description: "wildcard moved to another port",
+			servers:     []*networkingapi.Server{server(8080, "*")},
+			path:        "spec.servers[0].hosts[0]",
+			same:        false,
+		},
+	}
+	for _, tc := range testcases {
+		results := auditServers(t, tc.servers...)
+		assert.Equal(t, 1, len(results), tc.description)
+		assert.Equal(t, tc.path, results[0].Path, tc.description)
+		assert.Equal(t, tc.same, types.Fingerprint(results[0]) == types.Fingerprint(base[0]), tc.description)
+	}
+}
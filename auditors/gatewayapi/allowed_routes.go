@@ -0,0 +1,86 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewayapi
+
+import (
+	"context"
+	"fmt"
+
+	gateway "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"github.com/praetorian-inc/snowcat/auditors"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func init() {
+	auditors.Register(&allowedRoutesAuditor{})
+}
+
+type allowedRoutesAuditor struct{}
+
+func (a *allowedRoutesAuditor) ID() string {
+	return "gatewayapi-allowed-routes-all"
+}
+
+func (a *allowedRoutesAuditor) Name() string {
+	return "Gateway Accepts Routes from All Namespaces"
+}
+
+func (a *allowedRoutesAuditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	return gateways(resources)
+}
+
+func (a *allowedRoutesAuditor) AuditContext(ctx context.Context, _ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+	var results []types.AuditResult
+
+	for _, gw := range resources.KubernetesGateways {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for i, listener := range gw.Spec.Listeners {
+			if !allowsAllNamespaces(listener) {
+				continue
+			}
+			results = append(results, types.AuditResult{
+				Name:       a.Name(),
+				Severity:   types.Medium,
+				Resource:   gw.Namespace + ":" + gw.Name,
+				APIVersion: gateway.SchemeGroupVersion.String(),
+				Kind:       "Gateway",
+				Path:       fmt.Sprintf("spec.listeners[%d].allowedRoutes.namespaces.from", i),
+				ID:         "listener " + string(listener.Name),
+				Description: fmt.Sprintf("%s listener accepts routes from all namespaces, so any namespace can "+
+					"route traffic from the gateway", listener.Name),
+				Remediation: "set allowedRoutes.namespaces.from to Same, or to Selector with a selector " +
+					"matching only the namespaces trusted to attach routes",
+				References: []string{
+					securityModelURL,
+				},
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// allowsAllNamespaces returns true if routes from any namespace may attach
+// to the listener. Listeners default to routes from their own namespace.
+func allowsAllNamespaces(listener gateway.Listener) bool {
+	if listener.AllowedRoutes == nil || listener.AllowedRoutes.Namespaces == nil {
+		return false
+	}
+	from := listener.AllowedRoutes.Namespaces.From
+	return from != nil && *from == gateway.NamespacesFromAll
+}
@@ -0,0 +1,37 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gatewayapi provides auditor implementations that analyze
+// Kubernetes Gateway API resources (gateway.networking.k8s.io).
+package gatewayapi
+
+import (
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const (
+	// securityModelURL documents the roles and boundaries of the Gateway API.
+	securityModelURL = "https://gateway-api.sigs.k8s.io/concepts/security-model/"
+	// referenceGrantURL documents cross-namespace references.
+	referenceGrantURL = "https://gateway-api.sigs.k8s.io/api-types/referencegrant/"
+)
+
+// gateways returns the Gateway API Gateways in the form used by AuditResult.
+func gateways(resources types.Resources) []string {
+	var scope []string
+	for _, gateway := range resources.KubernetesGateways {
+		scope = append(scope, gateway.Namespace+":"+gateway.Name)
+	}
+	return scope
+}
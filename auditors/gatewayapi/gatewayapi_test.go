@@ -0,0 +1,116 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewayapi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	gateway "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func testResources() types.Resources {
+	all := gateway.NamespacesFromAll
+	same := gateway.NamespacesFromSame
+	hostname := gateway.Hostname("httpbin.example.com")
+	cert := gateway.ObjectName("httpbin-cert")
+
+	resources := types.NewResources()
+	resources.KubernetesGateways = []gateway.Gateway{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "default"},
+			Spec: gateway.GatewaySpec{
+				Listeners: []gateway.Listener{
+					{
+						Name:     "http",
+						Protocol: gateway.HTTPProtocolType,
+						AllowedRoutes: &gateway.AllowedRoutes{
+							Namespaces: &gateway.RouteNamespaces{From: &all},
+						},
+					},
+					{
+						Name:     "https",
+						Hostname: &hostname,
+						Protocol: gateway.HTTPSProtocolType,
+						AllowedRoutes: &gateway.AllowedRoutes{
+							Namespaces: &gateway.RouteNamespaces{From: &same},
+						},
+					},
+					{
+						Name:     "tcp",
+						Protocol: gateway.TCPProtocolType,
+					},
+				},
+			},
+		},
+	}
+	resources.ReferenceGrants = []gateway.ReferenceGrant{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "grant", Namespace: "default"},
+			Spec: gateway.ReferenceGrantSpec{
+				To: []gateway.ReferenceGrantTo{
+					{Kind: "Secret", Name: &cert},
+					{Kind: "Secret"},
+					{Kind: "Service"},
+				},
+			},
+		},
+	}
+	return resources
+}
+
+func TestAuditors(t *testing.T) {
+	type testcase struct {
+		auditor    types.ContextAuditor
+		paths      []string
+		severities []types.Severity
+	}
+
+	testcases := []testcase{
+		{
+			auditor:    &allowedRoutesAuditor{},
+			paths:      []string{"spec.listeners[0].allowedRoutes.namespaces.from"},
+			severities: []types.Severity{types.Medium},
+		},
+		{
+			auditor:    &referenceGrantAuditor{},
+			paths:      []string{"spec.to[1]", "spec.to[2]"},
+			severities: []types.Severity{types.High, types.Medium},
+		},
+		{
+			auditor:    &wildcardHostnameAuditor{},
+			paths:      []string{"spec.listeners[0].hostname"},
+			severities: []types.Severity{types.Low},
+		},
+	}
+
+	for _, tc := range testcases {
+		results, err := tc.auditor.AuditContext(context.Background(), types.Discovery{}, testResources())
+		assert.Equal(t, nil, err)
+
+		var paths []string
+		var severities []types.Severity
+		for _, res := range results {
+			paths = append(paths, res.Path)
+			severities = append(severities, res.Severity)
+		}
+		assert.Equal(t, tc.paths, paths)
+		assert.Equal(t, tc.severities, severities)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewayapi
+
+import (
+	"context"
+	"fmt"
+
+	gateway "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"github.com/praetorian-inc/snowcat/auditors"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func init() {
+	auditors.Register(&referenceGrantAuditor{})
+}
+
+type referenceGrantAuditor struct{}
+
+func (a *referenceGrantAuditor) ID() string {
+	return "gatewayapi-broad-reference-grant"
+}
+
+func (a *referenceGrantAuditor) Name() string {
+	return "Overly Broad ReferenceGrant"
+}
+
+func (a *referenceGrantAuditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	var scope []string
+	for _, grant := range resources.ReferenceGrants {
+		scope = append(scope, grant.Namespace+":"+grant.Name)
+	}
+	return scope
+}
+
+func (a *referenceGrantAuditor) AuditContext(ctx context.Context, _ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+	var results []types.AuditResult
+
+	for _, grant := range resources.ReferenceGrants {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for i, to := range grant.Spec.To {
+			if to.Name != nil && *to.Name != "" {
+				continue
+			}
+
+			// granting access to every secret exposes all certificates and
+			// credentials in the namespace
+			severity := types.Severity(types.Medium)
+			if to.Group == "" && to.Kind == "Secret" {
+				severity = types.High
+			}
+
+			results = append(results, types.AuditResult{
+				Name:       a.Name(),
+				Severity:   severity,
+				Resource:   grant.Namespace + ":" + grant.Name,
+				APIVersion: gateway.SchemeGroupVersion.String(),
+				Kind:       "ReferenceGrant",
+				Path:       fmt.Sprintf("spec.to[%d]", i),
+				ID:         "to " + kindName(to.Group, to.Kind),
+				Description: fmt.Sprintf("%s grant allows references to every %s in the %s namespace from %s",
+					grant.Name, kindName(to.Group, to.Kind), grant.Namespace, grantedFrom(grant.Spec.From)),
+				Remediation: "set the name of each object that may be referenced, " +
+					"so that only the intended objects are shared across namespaces",
+				References: []string{
+					referenceGrantURL,
+					securityModelURL,
+				},
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// kindName returns the kind qualified by its group, if any.
+func kindName(group gateway.Group, kind gateway.Kind) string {
+	if group == "" {
+		return string(kind)
+	}
+	return fmt.Sprintf("%s.%s", kind, group)
+}
+
+// grantedFrom describes the sources a ReferenceGrant applies to.
+func grantedFrom(from []gateway.ReferenceGrantFrom) string {
+	var desc string
+	for i, f := range from {
+		if i > 0 {
+			desc += ", "
+		}
+		desc += fmt.Sprintf("%s in %s", kindName(f.Group, f.Kind), f.Namespace)
+	}
+	return desc
+}
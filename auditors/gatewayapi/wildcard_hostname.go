@@ -0,0 +1,92 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewayapi
+
+import (
+	"context"
+	"fmt"
+
+	gateway "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"github.com/praetorian-inc/snowcat/auditors"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func init() {
+	auditors.Register(&wildcardHostnameAuditor{})
+}
+
+type wildcardHostnameAuditor struct{}
+
+func (a *wildcardHostnameAuditor) ID() string {
+	return "gatewayapi-wildcard-hostname"
+}
+
+func (a *wildcardHostnameAuditor) Name() string {
+	return "Overly Broad Gateway Listener Hostname"
+}
+
+func (a *wildcardHostnameAuditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	return gateways(resources)
+}
+
+func (a *wildcardHostnameAuditor) AuditContext(ctx context.Context, _ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+	var results []types.AuditResult
+
+	for _, gw := range resources.KubernetesGateways {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for i, listener := range gw.Spec.Listeners {
+			if !matchesHostnames(listener.Protocol) || !anyHostname(listener.Hostname) {
+				continue
+			}
+
+			results = append(results, types.AuditResult{
+				Name:        a.Name(),
+				Severity:    types.Low,
+				Resource:    gw.Namespace + ":" + gw.Name,
+				APIVersion:  gateway.SchemeGroupVersion.String(),
+				Kind:        "Gateway",
+				Path:        fmt.Sprintf("spec.listeners[%d].hostname", i),
+				ID:          "listener " + string(listener.Name),
+				Description: fmt.Sprintf("%s listener accepts any hostname", listener.Name),
+				Remediation: "set the listener hostname to the domain the gateway is meant to serve",
+				References: []string{
+					types.BestPracticesURL + "#avoid-overly-broad-hosts-configurations",
+					securityModelURL,
+				},
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// matchesHostnames returns true if listeners of the protocol match requests
+// by hostname. The hostname of TCP and UDP listeners is ignored.
+func matchesHostnames(protocol gateway.ProtocolType) bool {
+	switch protocol {
+	case gateway.HTTPProtocolType, gateway.HTTPSProtocolType, gateway.TLSProtocolType:
+		return true
+	default:
+		return false
+	}
+}
+
+// anyHostname returns true if the listener hostname matches every request.
+func anyHostname(hostname *gateway.Hostname) bool {
+	return hostname == nil || *hostname == "" || *hostname == "*"
+}
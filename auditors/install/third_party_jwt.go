@@ -17,6 +17,8 @@
 package install
 
 import (
+	"context"
+
 	log "github.com/sirupsen/logrus"
 
 	"github.com/praetorian-inc/snowcat/auditors"
@@ -29,11 +31,21 @@ func init() {
 
 type auditor struct{}
 
+func (a *auditor) ID() string {
+	return "install-third-party-jwt"
+}
+
 func (a *auditor) Name() string {
 	return "Weak Service Account Authentication"
 }
 
-func (a *auditor) Audit(_ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+// Scope reports the control plane installation as a whole, since the JWT
+// policy is a global setting and its result is not tied to a resource.
+func (a *auditor) Scope(types.Discovery, types.Resources) []string {
+	return []string{""}
+}
+
+func (a *auditor) AuditContext(ctx context.Context, _ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
 	var results []types.AuditResult
 
 	var policy string
@@ -41,6 +53,9 @@ func (a *auditor) Audit(_ types.Discovery, resources types.Resources) ([]types.A
 
 	// Iterate over all pods with the sidecar.istio.io/status annotation
 	for _, pod := range resources.Pods {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if pod.Annotations["sidecar.istio.io/status"] == "" {
 			continue
 		}
@@ -80,7 +95,13 @@ func (a *auditor) Audit(_ types.Discovery, resources types.Resources) ([]types.A
 	if foundSidecar && policy != "third-party-jwt" {
 		results = append(results, types.AuditResult{
 			Name:        a.Name(),
+			Severity:    types.Medium,
 			Description: "JWT policy not set to third-party-jwt",
+			Remediation: "reinstall istio with values.global.jwtPolicy set to third-party-jwt " +
+				"so that sidecars authenticate with audience-bound, expiring service account tokens",
+			References: []string{
+				types.BestPracticesURL + "#configure-third-party-service-account-tokens",
+			},
 		})
 	}
 
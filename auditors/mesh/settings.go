@@ -0,0 +1,239 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mesh provides auditor implementations that analyze the mesh-wide
+// settings of the MeshConfig, which apply to every workload in the mesh.
+package mesh
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	meshv1alpha1 "istio.io/api/mesh/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/praetorian-inc/snowcat/auditors"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const (
+	// meshConfigURL documents every field of the mesh configuration.
+	meshConfigURL = "https://istio.io/latest/docs/reference/config/istio.mesh.v1alpha1/"
+	// egressURL documents restricting outbound traffic to known services.
+	egressURL = "https://istio.io/latest/docs/tasks/traffic-management/egress/egress-control/"
+	// autoMtlsURL documents automatic mutual TLS.
+	autoMtlsURL = "https://istio.io/latest/docs/tasks/security/authentication/authn-policy/#auto-mutual-tls"
+	// topologyURL documents the trusted proxies in front of gateways.
+	topologyURL = "https://istio.io/latest/docs/ops/configuration/traffic-management/network-topologies/"
+
+	// defaultTrustDomain is the trust domain of meshes that do not set one.
+	defaultTrustDomain = "cluster.local"
+	// proxyConfigAnnotation overrides the proxy configuration of a workload.
+	proxyConfigAnnotation = "proxy.istio.io/config"
+)
+
+func init() {
+	auditors.Register(&auditor{})
+}
+
+type auditor struct{}
+
+func (a *auditor) ID() string {
+	return "mesh-settings"
+}
+
+func (a *auditor) Name() string {
+	return "Insecure Mesh Settings"
+}
+
+// Scope reports the ConfigMap holding the mesh configuration of each revision,
+// or the mesh as a whole if the configuration was read from a file or the
+// control plane.
+func (a *auditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	configs := resources.MeshConfigs()
+	if len(configs) == 0 {
+		return []string{resources.MeshConfigMap()}
+	}
+	var scope []string
+	for _, config := range configs {
+		scope = append(scope, config.ConfigMap)
+	}
+	return scope
+}
+
+// finding is an insecure mesh setting.
+type finding struct {
+	severity    types.Severity
+	path        string
+	description string
+	remediation string
+	references  []string
+}
+
+// Audit checks the mesh configuration of each revision, as revisions running
+// side by side, such as during a canary upgrade, each have their own.
+func (a *auditor) Audit(_ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+	configs := resources.MeshConfigs()
+	if len(configs) == 0 {
+		log.WithFields(log.Fields{
+			"auditor": a.Name(),
+		}).Info("no mesh config found")
+		return nil, nil
+	}
+
+	var results []types.AuditResult
+	for _, config := range configs {
+		for _, f := range insecureSettings(config.MeshConfig, resources) {
+			res := types.AuditResult{
+				Name:        a.Name(),
+				Severity:    f.severity,
+				Path:        f.path,
+				Description: f.description,
+				Remediation: f.remediation,
+				References:  append(f.references, meshConfigURL),
+			}
+			// the revision is only named when several revisions run
+			if len(configs) > 1 {
+				res.Revision = config.Revision
+			}
+			if config.ConfigMap != "" {
+				res.Resource = config.ConfigMap
+				res.APIVersion = "v1"
+				res.Kind = "ConfigMap"
+			} else if res.Revision != "" {
+				// configurations served by istiod share no resource name
+				res.ID = res.Revision + " " + f.path
+			}
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// insecureSettings returns the insecure settings of a mesh configuration.
+func insecureSettings(mesh *meshv1alpha1.MeshConfig, resources types.Resources) []finding {
+	var findings []finding
+
+	policy := mesh.GetOutboundTrafficPolicy()
+	if policy == nil || policy.Mode == meshv1alpha1.MeshConfig_OutboundTrafficPolicy_ALLOW_ANY {
+		findings = append(findings, finding{
+			severity:    types.Medium,
+			path:        "outboundTrafficPolicy.mode",
+			description: "outbound traffic policy allows workloads to reach any external host",
+			remediation: "set outboundTrafficPolicy.mode to REGISTRY_ONLY and add a ServiceEntry " +
+				"for each external service that workloads may reach",
+			references: []string{egressURL},
+		})
+	}
+
+	if td := mesh.GetTrustDomain(); td == "" || td == defaultTrustDomain {
+		findings = append(findings, finding{
+			severity:    types.Low,
+			path:        "trustDomain",
+			description: "mesh uses the default cluster.local trust domain, which is shared by every default installation",
+			remediation: "set trustDomain to a name unique to this mesh so its identities " +
+				"cannot be confused with those of other meshes",
+		})
+	}
+
+	if auto := mesh.GetEnableAutoMtls(); auto != nil && !auto.GetValue() {
+		findings = append(findings, finding{
+			severity:    types.Medium,
+			path:        "enableAutoMtls",
+			description: "automatic mutual TLS is disabled, so traffic is sent in plaintext unless a DestinationRule enables TLS",
+			remediation: "remove enableAutoMtls or set it to true",
+			references:  []string{autoMtlsURL},
+		})
+	}
+
+	switch mesh.GetPathNormalization().GetNormalization() {
+	case meshv1alpha1.MeshConfig_ProxyPathNormalization_NONE:
+		findings = append(findings, finding{
+			severity:    types.Medium,
+			path:        "pathNormalization.normalization",
+			description: "path normalization is disabled, so authorization policies can be bypassed with equivalent paths",
+			remediation: "set pathNormalization.normalization to MERGE_SLASHES or DECODE_AND_MERGE_SLASHES",
+			references: []string{
+				types.BestPracticesURL + "#customize-your-system-on-path-normalization",
+			},
+		})
+	case meshv1alpha1.MeshConfig_ProxyPathNormalization_DEFAULT,
+		meshv1alpha1.MeshConfig_ProxyPathNormalization_BASE:
+		findings = append(findings, finding{
+			severity:    types.Low,
+			path:        "pathNormalization.normalization",
+			description: "path normalization does not merge slashes or decode escaped slashes before authorization",
+			remediation: "set pathNormalization.normalization to MERGE_SLASHES or DECODE_AND_MERGE_SLASHES " +
+				"if backends treat these paths as equivalent",
+			references: []string{
+				types.BestPracticesURL + "#customize-your-system-on-path-normalization",
+			},
+		})
+	}
+
+	if hasGateways(resources) && mesh.GetDefaultConfig().GetGatewayTopology().GetNumTrustedProxies() == 0 &&
+		!annotatesTopology(resources) {
+		findings = append(findings, finding{
+			severity:    types.Low,
+			path:        "defaultConfig.gatewayTopology.numTrustedProxies",
+			description: "gateways do not know how many proxies are in front of them, so the client address seen by policies may be wrong",
+			remediation: "set defaultConfig.gatewayTopology.numTrustedProxies to the number of load balancers " +
+				"and proxies in front of the ingress gateways",
+			references: []string{topologyURL},
+		})
+	}
+
+	return findings
+}
+
+// hasGateways returns true if the mesh configures ingress gateways.
+func hasGateways(resources types.Resources) bool {
+	return len(resources.Gateways) > 0 || len(resources.KubernetesGateways) > 0
+}
+
+// annotatesTopology returns true if any workload sets the number of trusted
+// proxies in its proxy config annotation, which overrides the mesh default.
+func annotatesTopology(resources types.Resources) bool {
+	var workloads []metav1.ObjectMeta
+	for _, pod := range resources.Pods {
+		workloads = append(workloads, pod.ObjectMeta)
+	}
+	for _, deploy := range resources.Deployments {
+		workloads = append(workloads, deploy.Spec.Template.ObjectMeta)
+	}
+
+	for _, meta := range workloads {
+		annotation, ok := meta.Annotations[proxyConfigAnnotation]
+		if !ok {
+			continue
+		}
+		var config struct {
+			GatewayTopology struct {
+				NumTrustedProxies int `json:"numTrustedProxies"`
+			} `json:"gatewayTopology"`
+		}
+		if err := yaml.Unmarshal([]byte(annotation), &config); err != nil {
+			log.WithFields(log.Fields{
+				"workload": fmt.Sprintf("%s/%s", meta.Namespace, meta.Name),
+				"err":      err,
+			}).Debug("failed to parse proxy config annotation")
+			continue
+		}
+		if config.GatewayTopology.NumTrustedProxies > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesh
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	meshv1alpha1 "istio.io/api/mesh/v1alpha1"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func TestAudit(t *testing.T) {
+	type testcase struct {
+		mesh        string
+		gateways    bool
+		annotation  string
+		paths       []string
+		severities  []types.Severity
+		description string
+	}
+
+	testcases := []testcase{
+		{
+			description: "defaults",
+			mesh:        "{}",
+			gateways:    true,
+			paths: []string{
+				"outboundTrafficPolicy.mode",
+				"trustDomain",
+				"pathNormalization.normalization",
+				"defaultConfig.gatewayTopology.numTrustedProxies",
+			},
+			severities: []types.Severity{types.Medium, types.Low, types.Low, types.Low},
+		},
+		{
+			description: "hardened",
+			mesh: strings.Join([]string{
+				"outboundTrafficPolicy: {mode: REGISTRY_ONLY}",
+				"trustDomain: example.com",
+				"enableAutoMtls: true",
+				"pathNormalization: {normalization: MERGE_SLASHES}",
+				"defaultConfig: {gatewayTopology: {numTrustedProxies: 1}}",
+			}, "\n"),
+			gateways: true,
+		},
+		{
+			description: "insecure",
+			mesh: strings.Join([]string{
+				"outboundTrafficPolicy: {mode: ALLOW_ANY}",
+				"trustDomain: cluster.local",
+				"enableAutoMtls: false",
+				"pathNormalization: {normalization: NONE}",
+			}, "\n"),
+			paths: []string{
+				"outboundTrafficPolicy.mode",
+				"trustDomain",
+				"enableAutoMtls",
+				"pathNormalization.normalization",
+			},
+			severities: []types.Severity{types.Medium, types.Low, types.Medium, types.Medium},
+		},
+		{
+			description: "gateway topology annotation",
+			mesh: strings.Join([]string{
+				"outboundTrafficPolicy: {mode: REGISTRY_ONLY}",
+				"trustDomain: example.com",
+				"pathNormalization: {normalization: DECODE_AND_MERGE_SLASHES}",
+			}, "\n"),
+			gateways:   true,
+			annotation: "gatewayTopology:\n  numTrustedProxies: 2\n",
+		},
+	}
+
+	for _, tc := range testcases {
+		resources := types.NewResources()
+		resources.Load([]runtime.Object{
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+				Data:       map[string]string{"mesh": tc.mesh},
+			},
+		})
+		resources.LoadMeshConfigMap(types.Discovery{})
+		if tc.gateways {
+			resources.Gateways = []networkingv1alpha3.Gateway{
+				{ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: "istio-system"}},
+			}
+		}
+		if tc.annotation != "" {
+			resources.Pods = []corev1.Pod{
+				{ObjectMeta: metav1.ObjectMeta{
+					Name:        "istio-ingressgateway",
+					Namespace:   "istio-system",
+					Annotations: map[string]string{proxyConfigAnnotation: tc.annotation},
+				}},
+			}
+		}
+
+		results, err := (&auditor{}).Audit(types.Discovery{}, resources)
+		assert.Equal(t, nil, err, tc.description)
+
+		var paths []string
+		var severities []types.Severity
+		for _, res := range results {
+			assert.Equal(t, "istio-system:istio", res.Resource, tc.description)
+			assert.Equal(t, "ConfigMap", res.Kind, tc.description)
+			paths = append(paths, res.Path)
+			severities = append(severities, res.Severity)
+		}
+		assert.Equal(t, tc.paths, paths, tc.description)
+		assert.Equal(t, tc.severities, severities, tc.description)
+	}
+}
+
+func TestAuditRevisions(t *testing.T) {
+	resources := types.NewResources()
+	resources.Load([]runtime.Object{
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+			Data: map[string]string{"mesh": "{outboundTrafficPolicy: {mode: REGISTRY_ONLY}, trustDomain: example.com, " +
+				"pathNormalization: {normalization: MERGE_SLASHES}}"},
+		},
+	})
+	// the canary revision was upgraded with an insecure configuration
+	resources.SetRevisionMeshConfig("canary", &meshv1alpha1.MeshConfig{
+		TrustDomain: "example.com",
+		PathNormalization: &meshv1alpha1.MeshConfig_ProxyPathNormalization{
+			Normalization: meshv1alpha1.MeshConfig_ProxyPathNormalization_MERGE_SLASHES,
+		},
+	})
+	resources.LoadMeshConfigMap(types.Discovery{Revisions: []types.Revision{{Name: types.DefaultRevision}, {Name: "canary"}}})
+
+	a := &auditor{}
+	results, err := a.Audit(types.Discovery{}, resources)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(results))
+	assert.Equal(t, "canary", results[0].Revision)
+	assert.Equal(t, "outboundTrafficPolicy.mode", results[0].Path)
+	assert.Equal(t, "", results[0].Resource)
+	assert.Equal(t, []string{"", "istio-system:istio"}, a.Scope(types.Discovery{}, resources))
+}
+
+func TestAuditWithoutMeshConfig(t *testing.T) {
+	results, err := (&auditor{}).Audit(types.Discovery{}, types.NewResources())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(results))
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"fmt"
+	"strings"
+
+	networkingapi "istio.io/api/networking/v1alpha3"
+	networking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/praetorian-inc/snowcat/auditors"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const (
+	// eastWestURL documents the east-west gateways of multi-network meshes.
+	eastWestURL = "https://istio.io/latest/docs/setup/install/multicluster/multi-primary_multi-network/"
+	// tlsModeURL documents the TLS modes of gateway servers.
+	tlsModeURL = "https://istio.io/latest/docs/reference/config/networking/gateway/#ServerTLSSettings-TLSmode"
+)
+
+// broadHosts are the hosts that expose every service of the mesh, or of the
+// cluster, through a gateway.
+var broadHosts = map[string]bool{
+	"*":                   true,
+	"*.local":             true,
+	"*.cluster.local":     true,
+	"*.svc.cluster.local": true,
+}
+
+func init() {
+	auditors.Register(&autoPassthroughAuditor{})
+}
+
+type autoPassthroughAuditor struct{}
+
+func (a *autoPassthroughAuditor) ID() string {
+	return "multicluster-auto-passthrough"
+}
+
+func (a *autoPassthroughAuditor) Name() string {
+	return "Exposed Auto Passthrough Gateways"
+}
+
+func (a *autoPassthroughAuditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	var scope []string
+	for _, gateway := range resources.Gateways {
+		scope = append(scope, gateway.Namespace+":"+gateway.Name)
+	}
+	return scope
+}
+
+// Audit reports the gateway servers in AUTO_PASSTHROUGH mode, which forward
+// mutual TLS connections to whichever service is named in their SNI. They
+// are typically east-west gateways between the clusters of a multi-network
+// mesh, and are most exposed when they pass through every service and are
+// reachable from any address.
+func (a *autoPassthroughAuditor) Audit(_ types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+	var results []types.AuditResult
+
+	for _, gateway := range resources.Gateways {
+		exposed := exposingServices(gateway, resources.Services)
+
+		for i, server := range gateway.Spec.Servers {
+			if server.GetTls().GetMode() != networkingapi.ServerTLSSettings_AUTO_PASSTHROUGH {
+				continue
+			}
+
+			severity := types.Severity(types.Low)
+			reach := "services matching hosts " + strings.Join(server.Hosts, ", ")
+			for _, host := range server.Hosts {
+				if broadHosts[host] {
+					severity = types.Medium
+					reach = "every service of the mesh"
+					break
+				}
+			}
+			description := fmt.Sprintf("gateway passes mutual TLS connections through to %s", reach)
+			if len(exposed) > 0 {
+				severity = types.High
+				description += fmt.Sprintf(", and is reachable from any address through LoadBalancer Service %s",
+					strings.Join(exposed, ", "))
+			}
+
+			results = append(results, types.AuditResult{
+				Name:        a.Name(),
+				Severity:    severity,
+				Resource:    gateway.Namespace + ":" + gateway.Name,
+				APIVersion:  networking.SchemeGroupVersion.String(),
+				Kind:        "Gateway",
+				Path:        fmt.Sprintf("spec.servers[%d].tls.mode", i),
+				ID:          fmt.Sprintf("port %d hosts %s", server.GetPort().GetNumber(), strings.Join(server.Hosts, ",")),
+				Description: description,
+				Remediation: "restrict the server hosts to the services shared with other clusters, and set " +
+					"loadBalancerSourceRanges on the gateway Service to the addresses of the peer clusters",
+				References: []string{eastWestURL, tlsModeURL},
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// exposingServices returns the "namespace:name" of the LoadBalancer Services
+// without source ranges in front of the gateway workload. A Service is taken
+// to front the workload if either selector is a subset of the other.
+func exposingServices(gateway networking.Gateway, services []corev1.Service) []string {
+	selector := labels.Set(gateway.Spec.Selector)
+
+	var exposed []string
+	for _, svc := range services {
+		if svc.Spec.Type != corev1.ServiceTypeLoadBalancer || len(svc.Spec.LoadBalancerSourceRanges) > 0 {
+			continue
+		}
+		if len(svc.Spec.Selector) == 0 || len(selector) == 0 {
+			continue
+		}
+		svcSelector := labels.Set(svc.Spec.Selector)
+		if labels.SelectorFromSet(selector).Matches(svcSelector) || labels.SelectorFromSet(svcSelector).Matches(selector) {
+			exposed = append(exposed, svc.Namespace+":"+svc.Name)
+		}
+	}
+	return exposed
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	networkingapi "istio.io/api/networking/v1alpha3"
+	networking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func TestAutoPassthrough(t *testing.T) {
+	type testcase struct {
+		mode         networkingapi.ServerTLSSettings_TLSmode
+		hosts        []string
+		serviceType  corev1.ServiceType
+		sourceRanges []string
+		severities   []types.Severity
+		description  string
+	}
+
+	testcases := []testcase{
+		{
+			description: "mutual tls",
+			mode:        networkingapi.ServerTLSSettings_MUTUAL,
+			hosts:       []string{"*.local"},
+			serviceType: corev1.ServiceTypeLoadBalancer,
+		},
+		{
+			description: "narrow hosts",
+			mode:        networkingapi.ServerTLSSettings_AUTO_PASSTHROUGH,
+			hosts:       []string{"reviews.default.svc.cluster.local"},
+			serviceType: corev1.ServiceTypeClusterIP,
+			severities:  []types.Severity{types.Low},
+		},
+		{
+			description: "broad hosts",
+			mode:        networkingapi.ServerTLSSettings_AUTO_PASSTHROUGH,
+			hosts:       []string{"*.local"},
+			serviceType: corev1.ServiceTypeClusterIP,
+			severities:  []types.Severity{types.Medium},
+		},
+		{
+			description: "exposed load balancer",
+			mode:        networkingapi.ServerTLSSettings_AUTO_PASSTHROUGH,
+			hosts:       []string{"*.local"},
+			serviceType: corev1.ServiceTypeLoadBalancer,
+			severities:  []types.Severity{types.High},
+		},
+		{
+			description:  "restricted load balancer",
+			mode:         networkingapi.ServerTLSSettings_AUTO_PASSTHROUGH,
+			hosts:        []string{"*.local"},
+			serviceType:  corev1.ServiceTypeLoadBalancer,
+			sourceRanges: []string{"10.0.0.0/8"},
+			severities:   []types.Severity{types.Medium},
+		},
+	}
+
+	for _, tc := range testcases {
+		resources := types.NewResources()
+		resources.Gateways = []networking.Gateway{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "cross-network-gateway", Namespace: "istio-system"},
+				Spec: networkingapi.Gateway{
+					Selector: map[string]string{"istio": "eastwestgateway"},
+					Servers: []*networkingapi.Server{
+						{
+							Port:  &networkingapi.Port{Number: 15443, Name: "tls", Protocol: "TLS"},
+							Hosts: tc.hosts,
+							Tls:   &networkingapi.ServerTLSSettings{Mode: tc.mode},
+						},
+					},
+				},
+			},
+		}
+		resources.Services = []corev1.Service{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "istio-eastwestgateway", Namespace: "istio-system"},
+				Spec: corev1.ServiceSpec{
+					Type: tc.serviceType,
+					Selector: map[string]string{
+						"app":   "istio-eastwestgateway",
+						"istio": "eastwestgateway",
+					},
+					LoadBalancerSourceRanges: tc.sourceRanges,
+				},
+			},
+		}
+
+		results, err := (&autoPassthroughAuditor{}).Audit(types.Discovery{}, resources)
+		assert.Equal(t, nil, err, tc.description)
+
+		var severities []types.Severity
+		for _, res := range results {
+			assert.Equal(t, "istio-system:cross-network-gateway", res.Resource, tc.description)
+			assert.Equal(t, "spec.servers[0].tls.mode", res.Path, tc.description)
+			severities = append(severities, res.Severity)
+		}
+		assert.Equal(t, tc.severities, severities, tc.description)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// multiclusterURL documents the requirements shared by the clusters of a mesh.
+const multiclusterURL = "https://istio.io/latest/docs/setup/install/multicluster/before-you-begin/"
+
+// meshes groups the clusters by the ID of the mesh they are part of, in the
+// order the meshes are first seen. Clusters that do not set a mesh ID are
+// assumed to share a single mesh.
+func meshes(clusters []types.Cluster) [][]types.Cluster {
+	var groups [][]types.Cluster
+	index := make(map[string]int)
+	for _, cluster := range clusters {
+		id := cluster.Resources.MeshConfig.GetDefaultConfig().GetMeshId()
+		i, ok := index[id]
+		if !ok {
+			i = len(groups)
+			index[id] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], cluster)
+	}
+	return groups
+}
+
+// meshConfigResult returns a result against the ConfigMap holding the mesh
+// configuration of the cluster, when it is known.
+func meshConfigResult(cluster types.Cluster) types.AuditResult {
+	res := types.AuditResult{Cluster: cluster.Name}
+	if cm := cluster.Resources.MeshConfigMap(); cm != "" {
+		res.Resource = cm
+		res.APIVersion = "v1"
+		res.Kind = "ConfigMap"
+	}
+	return res
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"crypto/sha256"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/praetorian-inc/snowcat/auditors"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const (
+	// pluginCertsURL documents issuing the CA of every cluster from a shared root.
+	pluginCertsURL = "https://istio.io/latest/docs/tasks/security/cert-management/plugin-ca-cert/"
+
+	// rootCertConfigMap is the ConfigMap that istiod distributes the root
+	// certificate of the mesh in, to every namespace.
+	rootCertConfigMap = "istio-ca-root-cert"
+	// rootCertKey is the key of the root certificate in rootCertConfigMap.
+	rootCertKey = "root-cert.pem"
+)
+
+func init() {
+	auditors.Register(&rootCAAuditor{})
+}
+
+type rootCAAuditor struct{}
+
+func (a *rootCAAuditor) ID() string {
+	return "multicluster-root-ca"
+}
+
+func (a *rootCAAuditor) Name() string {
+	return "Mismatched Mesh Root Certificates"
+}
+
+// Audit does nothing, the root certificate of a cluster can only be checked
+// against its peers.
+func (a *rootCAAuditor) Audit(types.Discovery, types.Resources) ([]types.AuditResult, error) {
+	return nil, nil
+}
+
+// AuditClusters reports the clusters that share no root certificate with some
+// of the other clusters of their mesh. Clusters whose root certificate was not
+// collected are skipped.
+func (a *rootCAAuditor) AuditClusters(clusters []types.Cluster) ([]types.AuditResult, error) {
+	var results []types.AuditResult
+	for _, mesh := range meshes(clusters) {
+		roots := make([]map[string]bool, len(mesh))
+		configMaps := make([]*corev1.ConfigMap, len(mesh))
+		for i, cluster := range mesh {
+			configMaps[i] = rootCert(cluster)
+			if configMaps[i] != nil {
+				roots[i] = certFingerprints(configMaps[i].Data[rootCertKey])
+			}
+		}
+
+		for i, cluster := range mesh {
+			if len(roots[i]) == 0 {
+				continue
+			}
+			var mismatched []string
+			for j, peer := range mesh {
+				if i == j || len(roots[j]) == 0 {
+					continue
+				}
+				if !intersects(roots[i], roots[j]) {
+					mismatched = append(mismatched, peer.Name)
+				}
+			}
+			if len(mismatched) == 0 {
+				continue
+			}
+			sort.Strings(mismatched)
+
+			results = append(results, types.AuditResult{
+				Name:       a.Name(),
+				Severity:   types.High,
+				Cluster:    cluster.Name,
+				Resource:   configMaps[i].Namespace + ":" + configMaps[i].Name,
+				APIVersion: "v1",
+				Kind:       "ConfigMap",
+				Path:       "data." + rootCertKey,
+				Description: fmt.Sprintf("root certificate is not trusted by peer clusters %s, so mutual TLS "+
+					"between their workloads fails and is likely to be disabled to restore traffic",
+					strings.Join(mismatched, ", ")),
+				Remediation: "issue the CA certificate of every cluster of the mesh from a shared root certificate",
+				References:  []string{pluginCertsURL, multiclusterURL},
+			})
+		}
+	}
+	return results, nil
+}
+
+// rootCert returns the ConfigMap holding the root certificate of the cluster,
+// preferring the copy in the namespace of the control plane.
+func rootCert(cluster types.Cluster) *corev1.ConfigMap {
+	istions := cluster.Discovery.IstioNamespace
+	if istions == "" {
+		istions = types.DefaultRootNamespace
+	}
+
+	var found *corev1.ConfigMap
+	for i := range cluster.Resources.ConfigMaps {
+		cm := &cluster.Resources.ConfigMaps[i]
+		if cm.Name != rootCertConfigMap || cm.Data[rootCertKey] == "" {
+			continue
+		}
+		if cm.Namespace == istions {
+			return cm
+		}
+		if found == nil {
+			found = cm
+		}
+	}
+	return found
+}
+
+// certFingerprints returns the SHA-256 digests of the certificates in a PEM
+// bundle.
+func certFingerprints(bundle string) map[string]bool {
+	fingerprints := make(map[string]bool)
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return fingerprints
+		}
+		if block.Type == "CERTIFICATE" {
+			fingerprints[fmt.Sprintf("%x", sha256.Sum256(block.Bytes))] = true
+		}
+	}
+}
+
+// intersects returns true if the sets have an element in common.
+func intersects(a, b map[string]bool) bool {
+	for k := range a {
+		if b[k] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// rootPEM returns a self-signed PEM encoded root certificate.
+func rootPEM(t *testing.T, name string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// rootCluster returns a cluster whose root certificate bundle is roots.
+func rootCluster(name, roots string) types.Cluster {
+	resources := types.NewResources()
+	resources.ConfigMaps = []corev1.ConfigMap{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: rootCertConfigMap, Namespace: "default"},
+			Data:       map[string]string{rootCertKey: roots},
+		},
+	}
+	return types.Cluster{Name: name, Resources: resources}
+}
+
+func TestRootCA(t *testing.T) {
+	shared := rootPEM(t, "shared")
+	east := rootPEM(t, "east")
+	west := rootPEM(t, "west")
+
+	type testcase struct {
+		roots       map[string]string
+		flagged     []string
+		description string
+	}
+
+	testcases := []testcase{
+		{
+			description: "shared root",
+			roots:       map[string]string{"east": shared, "west": shared},
+		},
+		{
+			description: "root rotation",
+			roots:       map[string]string{"east": shared + east, "west": shared},
+		},
+		{
+			description: "separate roots",
+			roots:       map[string]string{"east": east, "west": west},
+			flagged:     []string{"east", "west"},
+		},
+		{
+			description: "missing root",
+			roots:       map[string]string{"east": east},
+		},
+	}
+
+	for _, tc := range testcases {
+		clusters := []types.Cluster{
+			rootCluster("east", tc.roots["east"]),
+			rootCluster("west", tc.roots["west"]),
+		}
+
+		results, err := (&rootCAAuditor{}).AuditClusters(clusters)
+		assert.Equal(t, nil, err, tc.description)
+
+		var flagged []string
+		for _, res := range results {
+			assert.Equal(t, "default:istio-ca-root-cert", res.Resource, tc.description)
+			assert.Equal(t, types.Severity(types.High), res.Severity, tc.description)
+			flagged = append(flagged, res.Cluster)
+		}
+		assert.Equal(t, tc.flagged, flagged, tc.description)
+	}
+}
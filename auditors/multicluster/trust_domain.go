@@ -0,0 +1,104 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/praetorian-inc/snowcat/auditors"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const (
+	// trustDomainURL documents accepting the identities of other trust domains.
+	trustDomainURL = "https://istio.io/latest/docs/tasks/security/authorization/authz-td-migration/"
+	// defaultTrustDomain is the trust domain of meshes that do not set one.
+	defaultTrustDomain = "cluster.local"
+)
+
+func init() {
+	auditors.Register(&trustDomainAuditor{})
+}
+
+type trustDomainAuditor struct{}
+
+func (a *trustDomainAuditor) ID() string {
+	return "multicluster-trust-domain"
+}
+
+func (a *trustDomainAuditor) Name() string {
+	return "Mismatched Mesh Trust Domains"
+}
+
+// Audit does nothing, the trust domain of a cluster can only be checked
+// against its peers.
+func (a *trustDomainAuditor) Audit(types.Discovery, types.Resources) ([]types.AuditResult, error) {
+	return nil, nil
+}
+
+// AuditClusters reports the clusters that do not accept the trust domain of
+// every other cluster of their mesh, either as their own or as an alias.
+// Clusters whose mesh configuration is unknown are skipped.
+func (a *trustDomainAuditor) AuditClusters(clusters []types.Cluster) ([]types.AuditResult, error) {
+	var results []types.AuditResult
+	for _, mesh := range meshes(clusters) {
+		for _, cluster := range mesh {
+			if cluster.Resources.MeshConfig == nil {
+				continue
+			}
+			accepted := map[string]bool{trustDomain(cluster): true}
+			for _, alias := range cluster.Resources.MeshConfig.GetTrustDomainAliases() {
+				accepted[alias] = true
+			}
+
+			var mismatched []string
+			for _, peer := range mesh {
+				if peer.Name == cluster.Name || peer.Resources.MeshConfig == nil {
+					continue
+				}
+				if td := trustDomain(peer); !accepted[td] {
+					mismatched = append(mismatched, fmt.Sprintf("%s (%s)", peer.Name, td))
+				}
+			}
+			if len(mismatched) == 0 {
+				continue
+			}
+			sort.Strings(mismatched)
+
+			res := meshConfigResult(cluster)
+			res.Name = a.Name()
+			res.Severity = types.Medium
+			res.Path = "trustDomain"
+			res.Description = fmt.Sprintf("trust domain %s does not accept the identities of peer clusters %s, "+
+				"so authorization policies reject or misattribute their workloads",
+				trustDomain(cluster), strings.Join(mismatched, ", "))
+			res.Remediation = "use the same trustDomain in every cluster of the mesh, or list the trust domains " +
+				"of the peer clusters in trustDomainAliases"
+			res.References = []string{trustDomainURL, multiclusterURL}
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// trustDomain returns the effective trust domain of a cluster.
+func trustDomain(cluster types.Cluster) string {
+	if td := cluster.Resources.MeshConfig.GetTrustDomain(); td != "" {
+		return td
+	}
+	return defaultTrustDomain
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// meshCluster returns a cluster configured with the mesh config.
+func meshCluster(name, mesh string) types.Cluster {
+	resources := types.NewResources()
+	resources.Load([]runtime.Object{
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+			Data:       map[string]string{"mesh": mesh},
+		},
+	})
+	resources.LoadMeshConfigMap(types.Discovery{})
+	return types.Cluster{Name: name, Resources: resources}
+}
+
+func TestTrustDomain(t *testing.T) {
+	type testcase struct {
+		meshes      map[string]string
+		flagged     []string
+		description string
+	}
+
+	testcases := []testcase{
+		{
+			description: "same trust domain",
+			meshes: map[string]string{
+				"east": "trustDomain: example.com",
+				"west": "trustDomain: example.com",
+			},
+		},
+		{
+			description: "default trust domains",
+			meshes: map[string]string{
+				"east": "{}",
+				"west": "trustDomain: cluster.local",
+			},
+		},
+		{
+			description: "mismatched trust domains",
+			meshes: map[string]string{
+				"east": "trustDomain: east.example.com",
+				"west": "trustDomain: west.example.com",
+			},
+			flagged: []string{"east", "west"},
+		},
+		{
+			description: "one-way alias",
+			meshes: map[string]string{
+				"east": "trustDomain: east.example.com\ntrustDomainAliases: [west.example.com]",
+				"west": "trustDomain: west.example.com",
+			},
+			flagged: []string{"west"},
+		},
+		{
+			description: "separate meshes",
+			meshes: map[string]string{
+				"east": "trustDomain: east.example.com\ndefaultConfig: {meshId: east}",
+				"west": "trustDomain: west.example.com\ndefaultConfig: {meshId: west}",
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		clusters := []types.Cluster{
+			meshCluster("east", tc.meshes["east"]),
+			meshCluster("west", tc.meshes["west"]),
+		}
+
+		results, err := (&trustDomainAuditor{}).AuditClusters(clusters)
+		assert.Equal(t, nil, err, tc.description)
+
+		var flagged []string
+		for _, res := range results {
+			assert.Equal(t, "istio-system:istio", res.Resource, tc.description)
+			assert.Equal(t, "trustDomain", res.Path, tc.description)
+			flagged = append(flagged, res.Cluster)
+		}
+		assert.Equal(t, tc.flagged, flagged, tc.description)
+	}
+}
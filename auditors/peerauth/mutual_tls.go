@@ -17,7 +17,9 @@
 package peerauth
 
 import (
+	"context"
 	"fmt"
+	"sort"
 
 	"github.com/praetorian-inc/snowcat/auditors"
 	"github.com/praetorian-inc/snowcat/pkg/types"
@@ -29,11 +31,39 @@ func init() {
 
 type auditor struct{}
 
+func (a *auditor) ID() string {
+	return "peerauth-permissive-mtls"
+}
+
 func (a *auditor) Name() string {
 	return "Permissive Mutual TLS"
 }
 
-func (a *auditor) Audit(disco types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+// namespaces returns the sorted names of all namespaces that are known or
+// hold a PeerAuthentication.
+func namespaces(resources types.Resources) []string {
+	seen := make(map[string]struct{})
+	for _, ns := range resources.Namespaces {
+		seen[ns.Name] = struct{}{}
+	}
+	for _, policy := range resources.PeerAuthentications {
+		seen[policy.Namespace] = struct{}{}
+	}
+
+	// iterate namespaces in order so results are reproducible across runs
+	names := make([]string, 0, len(seen))
+	for ns := range seen {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *auditor) Scope(_ types.Discovery, resources types.Resources) []string {
+	return namespaces(resources)
+}
+
+func (a *auditor) AuditContext(ctx context.Context, disco types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
 	var results []types.AuditResult
 
 	namespaceSafety := make(map[string]bool)
@@ -48,20 +78,28 @@ func (a *auditor) Audit(disco types.Discovery, resources types.Resources) ([]typ
 	}
 
 	// If a strict policy is configured in the root namespace, it applies mesh-wide
-	rootns := disco.IstioNamespace
-	if rootns == "" {
-		rootns = "istio-system"
-	}
-	if _, safe := namespaceSafety[rootns]; safe {
+	rootns := types.RootNamespace(disco, resources)
+	if namespaceSafety[rootns] {
 		return results, nil
 	}
 
-	for ns, safe := range namespaceSafety {
-		if !safe {
+	for _, ns := range namespaces(resources) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !namespaceSafety[ns] {
 			results = append(results, types.AuditResult{
 				Name:        a.Name(),
+				Severity:    types.High,
 				Resource:    ns,
+				APIVersion:  "v1",
+				Kind:        "Namespace",
 				Description: fmt.Sprintf("%s namespace missing PeerAuthentication policy", ns),
+				Remediation: fmt.Sprintf("add a PeerAuthentication with mtls.mode STRICT to the %s namespace, "+
+					"or to the %s root namespace to apply it mesh-wide", ns, rootns),
+				References: []string{
+					types.BestPracticesURL + "#mutual-tls",
+				},
 			})
 		}
 	}
@@ -0,0 +1,91 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerauth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	meshv1alpha1 "istio.io/api/mesh/v1alpha1"
+	securityapi "istio.io/api/security/v1beta1"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func TestAudit(t *testing.T) {
+	type testcase struct {
+		description string
+		strictIn    string
+		rootns      string
+		resources   []string
+	}
+
+	testcases := []testcase{
+		{"no strict policy", "", "", []string{"apps", "istio-config", "istio-system"}},
+		{"strict in default root namespace", "istio-system", "", nil},
+		{"strict in configured root namespace", "istio-config", "istio-config", nil},
+		{"strict outside configured root namespace", "istio-system", "istio-config", []string{"apps", "istio-config"}},
+	}
+
+	for _, tc := range testcases {
+		resources := types.NewResources()
+		resources.Namespaces = []corev1.Namespace{
+			{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "istio-config"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "istio-system"}},
+		}
+		if tc.strictIn != "" {
+			resources.PeerAuthentications = []securityv1beta1.PeerAuthentication{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: tc.strictIn},
+					Spec: securityapi.PeerAuthentication{
+						Mtls: &securityapi.PeerAuthentication_MutualTLS{
+							Mode: securityapi.PeerAuthentication_MutualTLS_STRICT,
+						},
+					},
+				},
+			}
+		}
+		if tc.rootns != "" {
+			resources.MeshConfig = &meshv1alpha1.MeshConfig{RootNamespace: tc.rootns}
+		}
+
+		results, err := (&auditor{}).AuditContext(context.Background(), types.Discovery{}, resources)
+		assert.Equal(t, nil, err, tc.description)
+
+		var flagged []string
+		for _, res := range results {
+			flagged = append(flagged, res.Resource)
+		}
+		assert.Equal(t, tc.resources, flagged, tc.description)
+	}
+}
+
+func TestAuditContextCanceled(t *testing.T) {
+	resources := types.NewResources()
+	resources.Namespaces = []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := (&auditor{}).AuditContext(ctx, types.Discovery{}, resources)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, len(results))
+}
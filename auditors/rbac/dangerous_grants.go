@@ -0,0 +1,184 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rbac provides auditor implementations that analyze the permissions
+// of the service account token mounted in the meshed workload running snowcat.
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/praetorian-inc/snowcat/auditors"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// rbacURL documents good practices for Kubernetes RBAC.
+const rbacURL = "https://kubernetes.io/docs/concepts/security/rbac-good-practices/"
+
+func init() {
+	auditors.Register(&auditor{})
+}
+
+// dangerous are the severities of sensitive permissions when granted in a
+// single namespace. permissions on namespaced resources granted across all
+// namespaces are rated one level higher, while the severities of cluster
+// scoped resources, which can only be granted across the cluster, are given
+// as is.
+var dangerous = map[types.Permission]types.Severity{
+	{Verb: "get", Resource: "secrets"}:                                                                   types.High,
+	{Verb: "list", Resource: "secrets"}:                                                                  types.High,
+	{Verb: "create", Resource: "pods"}:                                                                   types.Medium,
+	{Verb: "create", Resource: "pods", Subresource: "exec"}:                                              types.High,
+	{Verb: "create", Resource: "serviceaccounts", Subresource: "token"}:                                  types.High,
+	{Verb: "impersonate", Resource: "serviceaccounts"}:                                                   types.High,
+	{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "rolebindings"}:                       types.High,
+	{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"}:                types.Critical,
+	{Verb: "escalate", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"}:                     types.Critical,
+	{Verb: "create", Group: "admissionregistration.k8s.io", Resource: "mutatingwebhookconfigurations"}:   types.Critical,
+	{Verb: "patch", Group: "admissionregistration.k8s.io", Resource: "mutatingwebhookconfigurations"}:    types.Critical,
+	{Verb: "delete", Group: "admissionregistration.k8s.io", Resource: "validatingwebhookconfigurations"}: types.High,
+	{Verb: "create", Group: "networking.istio.io", Resource: "envoyfilters"}:                             types.High,
+	{Verb: "create", Group: "extensions.istio.io", Resource: "wasmplugins"}:                              types.High,
+	{Verb: "create", Group: "networking.istio.io", Resource: "virtualservices"}:                          types.Medium,
+	{Verb: "create", Group: "networking.istio.io", Resource: "destinationrules"}:                         types.Medium,
+	{Verb: "create", Group: "networking.istio.io", Resource: "sidecars"}:                                 types.Medium,
+	{Verb: "create", Group: "security.istio.io", Resource: "authorizationpolicies"}:                      types.High,
+	{Verb: "delete", Group: "security.istio.io", Resource: "authorizationpolicies"}:                      types.High,
+	{Verb: "create", Group: "security.istio.io", Resource: "peerauthentications"}:                        types.High,
+	{Verb: "delete", Group: "security.istio.io", Resource: "peerauthentications"}:                        types.High,
+}
+
+// clusterScoped are the resources of dangerous that are not namespaced.
+var clusterScoped = map[string]bool{
+	"clusterrolebindings":             true,
+	"clusterroles":                    true,
+	"mutatingwebhookconfigurations":   true,
+	"validatingwebhookconfigurations": true,
+}
+
+type auditor struct{}
+
+func (a *auditor) ID() string {
+	return "rbac-dangerous-grants"
+}
+
+func (a *auditor) Name() string {
+	return "Dangerous Service Account Permissions"
+}
+
+func (a *auditor) Scope(disco types.Discovery, resources types.Resources) []string {
+	if disco.RBAC == nil || !meshed(disco.RBAC.ServiceAccount, resources) {
+		return nil
+	}
+	return []string{disco.RBAC.ServiceAccount}
+}
+
+func (a *auditor) Audit(disco types.Discovery, resources types.Resources) ([]types.AuditResult, error) {
+	var results []types.AuditResult
+	if disco.RBAC == nil || !meshed(disco.RBAC.ServiceAccount, resources) {
+		return results, nil
+	}
+
+	// a permission granted across all namespaces implies the same permission
+	// in a single namespace, which is not reported separately
+	clusterWide := make(map[types.Permission]bool)
+	for _, p := range disco.RBAC.Permissions {
+		if p.Allowed && p.Namespace == "" {
+			clusterWide[rule(p)] = true
+		}
+	}
+
+	for _, p := range disco.RBAC.Permissions {
+		severity, ok := dangerous[rule(p)]
+		if !p.Allowed || !ok {
+			continue
+		}
+		description := fmt.Sprintf("service account token of the workload may %s", p.String())
+		switch {
+		case p.Namespace != "":
+			if clusterWide[rule(p)] {
+				continue
+			}
+		case !clusterScoped[p.Resource]:
+			severity = raise(severity)
+			description += " across the cluster"
+		}
+
+		results = append(results, types.AuditResult{
+			Name:        a.Name(),
+			Severity:    severity,
+			Resource:    disco.RBAC.ServiceAccount,
+			APIVersion:  "v1",
+			Kind:        "ServiceAccount",
+			ID:          p.String(),
+			Description: description,
+			Remediation: fmt.Sprintf("remove the %s permission from the roles bound to the %s service account, "+
+				"or disable automountServiceAccountToken if the workload does not use the Kubernetes API",
+				p.String(), disco.RBAC.ServiceAccount),
+			References: []string{rbacURL},
+		})
+	}
+	return results, nil
+}
+
+// meshed reports whether the workloads of a "namespace:name" service account
+// run with a sidecar, as seen on their pods, or if none were collected, as
+// implied by the injection labels of their namespace.
+func meshed(serviceAccount string, resources types.Resources) bool {
+	namespace, name := serviceAccount, ""
+	if i := strings.Index(serviceAccount, ":"); i >= 0 {
+		namespace, name = serviceAccount[:i], serviceAccount[i+1:]
+	}
+
+	var found bool
+	for _, pod := range resources.Pods {
+		account := pod.Spec.ServiceAccountName
+		if account == "" {
+			account = "default"
+		}
+		if pod.Namespace != namespace || (name != "" && account != name) {
+			continue
+		}
+		found = true
+		if pod.Annotations["sidecar.istio.io/status"] != "" {
+			return true
+		}
+		for _, container := range pod.Spec.Containers {
+			if container.Name == "istio-proxy" {
+				return true
+			}
+		}
+	}
+	if found {
+		return false
+	}
+	return resources.NamespaceRevision(namespace) != ""
+}
+
+// rule returns the permission without its namespace and result, as a key of
+// dangerous.
+func rule(p types.Permission) types.Permission {
+	p.Namespace = ""
+	p.Allowed = false
+	return p
+}
+
+// raise returns the next higher severity, up to critical.
+func raise(severity types.Severity) types.Severity {
+	if severity >= types.Critical {
+		return types.Critical
+	}
+	return severity + 1
+}
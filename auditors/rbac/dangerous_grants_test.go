@@ -0,0 +1,138 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// pod returns a pod of the service account, with a sidecar if injected.
+func pod(namespace, serviceAccount string, injected bool) *corev1.Pod {
+	p := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: serviceAccount, Namespace: namespace},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: serviceAccount,
+			Containers:         []corev1.Container{{Name: "app"}},
+		},
+	}
+	if injected {
+		p.Spec.Containers = append(p.Spec.Containers, corev1.Container{Name: "istio-proxy"})
+	}
+	return p
+}
+
+func TestAudit(t *testing.T) {
+	disco := types.Discovery{
+		RBAC: &types.RBAC{
+			ServiceAccount: "apps:httpbin",
+			Permissions: []types.Permission{
+				{Verb: "get", Resource: "secrets", Namespace: "apps", Allowed: true},
+				{Verb: "get", Resource: "secrets", Allowed: false},
+				{Verb: "create", Group: "networking.istio.io", Resource: "envoyfilters", Namespace: "apps", Allowed: true},
+				{Verb: "create", Group: "networking.istio.io", Resource: "envoyfilters", Allowed: true},
+				{Verb: "create", Resource: "pods", Subresource: "exec", Namespace: "apps", Allowed: false},
+				{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings", Allowed: true},
+				{Verb: "delete", Group: "admissionregistration.k8s.io", Resource: "validatingwebhookconfigurations", Allowed: true},
+				{Verb: "list", Resource: "configmaps", Namespace: "apps", Allowed: true},
+			},
+		},
+	}
+
+	resources := types.NewResources()
+	resources.Load([]runtime.Object{pod("apps", "httpbin", true)})
+
+	results, err := (&auditor{}).Audit(disco, resources)
+	assert.Equal(t, nil, err)
+
+	var ids, descriptions []string
+	var severities []types.Severity
+	for _, res := range results {
+		assert.Equal(t, "apps:httpbin", res.Resource)
+		assert.Equal(t, "", res.Path)
+		ids = append(ids, res.ID)
+		descriptions = append(descriptions, res.Description)
+		severities = append(severities, res.Severity)
+	}
+	assert.Equal(t, []string{
+		"get secrets in apps",
+		"create envoyfilters.networking.istio.io",
+		"create clusterrolebindings.rbac.authorization.k8s.io",
+		"delete validatingwebhookconfigurations.admissionregistration.k8s.io",
+	}, ids)
+	// only namespaced resources granted across all namespaces are raised
+	assert.Equal(t, []types.Severity{types.High, types.Critical, types.Critical, types.High}, severities)
+	assert.Equal(t, []string{
+		"service account token of the workload may get secrets in apps",
+		"service account token of the workload may create envoyfilters.networking.istio.io across the cluster",
+		"service account token of the workload may create clusterrolebindings.rbac.authorization.k8s.io",
+		"service account token of the workload may delete validatingwebhookconfigurations.admissionregistration.k8s.io",
+	}, descriptions)
+}
+
+func TestAuditMeshed(t *testing.T) {
+	injected := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "apps",
+		Labels: map[string]string{types.InjectionLabel: "enabled"},
+	}}
+	plain := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}
+
+	type testcase struct {
+		description string
+		objects     []runtime.Object
+		meshed      bool
+	}
+	testcases := []testcase{
+		{"pod with a sidecar", []runtime.Object{pod("apps", "httpbin", true)}, true},
+		{"pod without a sidecar", []runtime.Object{pod("apps", "httpbin", false)}, false},
+		{"pod without a sidecar in an injected namespace", []runtime.Object{injected, pod("apps", "httpbin", false)}, false},
+		{"sidecar on a pod of another service account", []runtime.Object{pod("apps", "other", true)}, false},
+		{"injected namespace", []runtime.Object{injected}, true},
+		{"namespace without injection", []runtime.Object{plain}, false},
+		{"nothing known", nil, false},
+	}
+	disco := types.Discovery{
+		RBAC: &types.RBAC{
+			ServiceAccount: "apps:httpbin",
+			Permissions: []types.Permission{
+				{Verb: "get", Resource: "secrets", Namespace: "apps", Allowed: true},
+			},
+		},
+	}
+	for _, tc := range testcases {
+		resources := types.NewResources()
+		resources.Load(tc.objects)
+
+		a := &auditor{}
+		results, err := a.Audit(disco, resources)
+		assert.Equal(t, nil, err, tc.description)
+		assert.Equal(t, tc.meshed, len(results) == 1, tc.description)
+		assert.Equal(t, tc.meshed, len(a.Scope(disco, resources)) == 1, tc.description)
+	}
+}
+
+func TestAuditWithoutProbe(t *testing.T) {
+	a := &auditor{}
+	results, err := a.Audit(types.Discovery{}, types.NewResources())
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 0, len(results))
+	assert.Equal(t, 0, len(a.Scope(types.Discovery{}, types.NewResources())))
+}
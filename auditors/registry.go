@@ -25,12 +25,12 @@
 //      _ "github.com/praetorian-inc/snowcat/auditors/peerauth"
 //  )
 //
-//  auditors, err := auditors.New(types.Config{})
+//  selected, err := auditors.Select([]string{"authz"}, nil)
 //  if err != nil {
 //      // handle error
 //  }
-//  for _, auditor := range auditors {
-//      res, err := auditor.Audit(disco, resources)
+//  opts := auditors.RunOptions{Workers: 4, Timeout: time.Minute}
+//  for _, outcome := range auditors.Run(ctx, selected, disco, resources, opts) {
 //      ...
 //  }
 //
@@ -40,12 +40,14 @@ package auditors
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/praetorian-inc/snowcat/pkg/types"
 )
 
-// Register makes an auditor available with the provided name. If register is
+// Register makes an auditor available with the provided ID. If register is
 // called twice or if the driver is nil, if panics. Register() is typically
 // called in the auditor implementation's init() function to allow for easy
 // importing of each auditor.
@@ -57,25 +59,80 @@ func Register(auditor types.Auditor) {
 		panic("Registered auditor is nil")
 	}
 
-	name := auditor.Name()
-	if _, ok := registry[name]; ok {
-		panic(fmt.Errorf("auditor %s already registered", name))
+	id := auditor.ID()
+	switch auditor.(type) {
+	case types.BasicAuditor, types.ContextAuditor:
+	default:
+		panic(fmt.Errorf("auditor %s implements neither Audit nor AuditContext", id))
 	}
-	registry[name] = auditor
+	if _, ok := registry[id]; ok {
+		panic(fmt.Errorf("auditor %s already registered", id))
+	}
+	registry[id] = auditor
 }
 
-// All returns a list of all auditors.
+// All returns a list of all auditors, ordered by ID.
 func All() []types.Auditor {
-	registryMu.Lock()
-	defer registryMu.Unlock()
+	registryMu.RLock()
+	defer registryMu.RUnlock()
 
 	var auditors []types.Auditor
 	for _, v := range registry {
 		auditors = append(auditors, v)
 	}
+	sort.Slice(auditors, func(i, j int) bool {
+		return auditors[i].ID() < auditors[j].ID()
+	})
 	return auditors
 }
 
+// Category returns the category of an auditor ID, which is the portion of the
+// ID before the first "-" (e.g. "authz" for "authz-allow-negative").
+func Category(id string) string {
+	return strings.SplitN(id, "-", 2)[0]
+}
+
+// Select returns the auditors, ordered by ID, that match an entry in enable
+// and do not match an entry in disable. Entries match either an auditor ID or
+// a category. An empty enable list selects every auditor. Select returns an
+// error if an entry matches no registered auditor.
+func Select(enable, disable []string) ([]types.Auditor, error) {
+	all := All()
+
+	known := make(map[string]struct{})
+	for _, auditor := range all {
+		known[auditor.ID()] = struct{}{}
+		known[Category(auditor.ID())] = struct{}{}
+	}
+
+	for _, entry := range append(append([]string{}, enable...), disable...) {
+		if _, ok := known[entry]; !ok {
+			return nil, fmt.Errorf("no auditor matches %q", entry)
+		}
+	}
+
+	matches := func(auditor types.Auditor, entries []string) bool {
+		for _, entry := range entries {
+			if entry == auditor.ID() || entry == Category(auditor.ID()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var selected []types.Auditor
+	for _, auditor := range all {
+		if len(enable) > 0 && !matches(auditor, enable) {
+			continue
+		}
+		if matches(auditor, disable) {
+			continue
+		}
+		selected = append(selected, auditor)
+	}
+	return selected, nil
+}
+
 var (
 	registry   = make(map[string]types.Auditor)
 	registryMu sync.RWMutex
@@ -0,0 +1,114 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditors
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+type fakeAuditor struct {
+	id string
+}
+
+func (a *fakeAuditor) ID() string {
+	return a.id
+}
+
+func (a *fakeAuditor) Name() string {
+	return a.id
+}
+
+func (a *fakeAuditor) Audit(types.Discovery, types.Resources) ([]types.AuditResult, error) {
+	return nil, nil
+}
+
+func init() {
+	Register(&fakeAuditor{id: "gateway-broad-hosts"})
+	Register(&fakeAuditor{id: "authz-deny-positive"})
+	Register(&fakeAuditor{id: "authz-allow-negative"})
+}
+
+func ids(auditors []types.Auditor) []string {
+	var res []string
+	for _, auditor := range auditors {
+		res = append(res, auditor.ID())
+	}
+	return res
+}
+
+func TestAll(t *testing.T) {
+	expected := []string{"authz-allow-negative", "authz-deny-positive", "gateway-broad-hosts"}
+	assert.Equal(t, expected, ids(All()))
+}
+
+func TestSelect(t *testing.T) {
+	type testcase struct {
+		enable   []string
+		disable  []string
+		expected []string
+	}
+
+	testcases := []testcase{
+		{
+			expected: []string{"authz-allow-negative", "authz-deny-positive", "gateway-broad-hosts"},
+		},
+		{
+			enable:   []string{"authz"},
+			expected: []string{"authz-allow-negative", "authz-deny-positive"},
+		},
+		{
+			enable:   []string{"authz"},
+			disable:  []string{"authz-deny-positive"},
+			expected: []string{"authz-allow-negative"},
+		},
+		{
+			disable:  []string{"authz"},
+			expected: []string{"gateway-broad-hosts"},
+		},
+	}
+	for i, tc := range testcases {
+		selected, err := Select(tc.enable, tc.disable)
+		if err != nil {
+			t.Fatalf("[%d] unexpected error: %s", i, err)
+		}
+		assert.Equal(t, tc.expected, ids(selected))
+	}
+
+	_, err := Select([]string{"mesh"}, nil)
+	assert.NotEqual(t, nil, err)
+}
+
+// namedAuditor has neither Audit nor AuditContext.
+type namedAuditor struct{}
+
+func (a *namedAuditor) ID() string {
+	return "test-named"
+}
+
+func (a *namedAuditor) Name() string {
+	return "test-named"
+}
+
+func TestRegisterWithoutAudit(t *testing.T) {
+	defer func() {
+		assert.NotEqual(t, nil, recover())
+		assert.Equal(t, 3, len(All()))
+	}()
+	Register(&namedAuditor{})
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// Outcome is the result of running a single auditor.
+type Outcome struct {
+	// Auditor is the auditor that was run.
+	Auditor types.Auditor
+	// Results are the results reported by the auditor.
+	Results []types.AuditResult
+	// Err is set if the auditor failed, timed out or panicked.
+	Err error
+	// Duration is how long the auditor ran for.
+	Duration time.Duration
+	// Cluster is the name of the cluster the auditor ran against. It is
+	// empty for a single cluster and for cross-cluster audits.
+	Cluster string
+}
+
+// RunOptions controls how auditors are run.
+type RunOptions struct {
+	// Workers is the maximum number of auditors run concurrently. Values
+	// less than one run a single auditor at a time.
+	Workers int
+	// Timeout bounds the run time of each auditor. Zero disables the timeout.
+	Timeout time.Duration
+}
+
+// Run runs the auditors concurrently on a bounded pool of workers and returns
+// their outcomes in the order of the auditors. An auditor that panics, times
+// out or is canceled through ctx is reported as failed without affecting the
+// others.
+//
+// Auditors that implement types.ContextAuditor are expected to stop once
+// their context is done. Others cannot be interrupted, so Run stops waiting
+// for them and leaves them to finish in the background.
+func Run(ctx context.Context, auditors []types.Auditor, disco types.Discovery, resources types.Resources, opts RunOptions) []Outcome {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	outcomes := make([]Outcome, len(auditors))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				outcomes[i] = runOne(ctx, auditors[i], disco, resources, opts.Timeout)
+			}
+		}()
+	}
+
+	for i := range auditors {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return outcomes
+}
+
+// RunClusters runs the auditors against each cluster like Run, tagging the
+// outcomes and results with the name of the cluster. When there is more than
+// one cluster, the auditors that implement types.ClusterAuditor are then run
+// once more against all of the clusters together. Outcomes are ordered by
+// cluster, followed by the cross-cluster outcomes.
+func RunClusters(ctx context.Context, auditors []types.Auditor, clusters []types.Cluster, opts RunOptions) []Outcome {
+	var outcomes []Outcome
+	for _, cluster := range clusters {
+		for _, outcome := range Run(ctx, auditors, cluster.Discovery, cluster.Resources, opts) {
+			outcome.Cluster = cluster.Name
+			// copy the results, an auditor may return the same slice for
+			// every cluster
+			results := make([]types.AuditResult, len(outcome.Results))
+			for i, res := range outcome.Results {
+				res.Cluster = cluster.Name
+				results[i] = res
+			}
+			outcome.Results = results
+			outcomes = append(outcomes, outcome)
+		}
+	}
+	if len(clusters) < 2 {
+		return outcomes
+	}
+
+	var cross []types.Auditor
+	for _, auditor := range auditors {
+		if ca, ok := auditor.(types.ClusterAuditor); ok {
+			cross = append(cross, clusterAudit{ca, clusters})
+		}
+	}
+	for _, outcome := range Run(ctx, cross, types.Discovery{}, types.NewResources(), opts) {
+		outcome.Auditor = outcome.Auditor.(clusterAudit).ClusterAuditor
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// clusterAudit adapts a cross-cluster audit to the Auditor interface so it is
+// bounded and recovered like any other auditor.
+type clusterAudit struct {
+	types.ClusterAuditor
+	clusters []types.Cluster
+}
+
+func (a clusterAudit) Audit(types.Discovery, types.Resources) ([]types.AuditResult, error) {
+	return a.AuditClusters(a.clusters)
+}
+
+// runOne runs a single auditor, bounded by ctx and timeout.
+func runOne(ctx context.Context, auditor types.Auditor, disco types.Discovery, resources types.Resources, timeout time.Duration) Outcome {
+	outcome := Outcome{Auditor: auditor}
+	start := time.Now()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// the channel is buffered so an abandoned auditor can still finish
+	done := make(chan Outcome, 1)
+	go func() {
+		res := Outcome{Auditor: auditor}
+		defer func() {
+			if r := recover(); r != nil {
+				res.Results = nil
+				res.Err = fmt.Errorf("auditor panicked: %v\n%s", r, debug.Stack())
+			}
+			done <- res
+		}()
+
+		switch a := auditor.(type) {
+		case types.ContextAuditor:
+			res.Results, res.Err = a.AuditContext(ctx, disco, resources)
+		case types.BasicAuditor:
+			res.Results, res.Err = a.Audit(disco, resources)
+		default:
+			res.Err = fmt.Errorf("auditor %s implements neither Audit nor AuditContext", auditor.ID())
+		}
+	}()
+
+	select {
+	case outcome = <-done:
+	case <-ctx.Done():
+		outcome.Err = ctx.Err()
+	}
+	if timeout > 0 && errors.Is(outcome.Err, context.DeadlineExceeded) {
+		outcome.Results = nil
+		outcome.Err = fmt.Errorf("auditor timed out after %s: %w", timeout, outcome.Err)
+	}
+	outcome.Duration = time.Since(start)
+	return outcome
+}
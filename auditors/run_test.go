@@ -0,0 +1,127 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auditors
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// funcAuditor runs audit when audited, honoring the context it is given.
+type funcAuditor struct {
+	fakeAuditor
+	audit func(ctx context.Context) ([]types.AuditResult, error)
+}
+
+func (a *funcAuditor) AuditContext(ctx context.Context, _ types.Discovery, _ types.Resources) ([]types.AuditResult, error) {
+	return a.audit(ctx)
+}
+
+func TestRun(t *testing.T) {
+	finding := []types.AuditResult{{Description: "finding"}}
+
+	selected := []types.Auditor{
+		&funcAuditor{
+			fakeAuditor: fakeAuditor{id: "test-ok"},
+			audit: func(context.Context) ([]types.AuditResult, error) {
+				return finding, nil
+			},
+		},
+		&funcAuditor{
+			fakeAuditor: fakeAuditor{id: "test-panic"},
+			audit: func(context.Context) ([]types.AuditResult, error) {
+				panic("boom")
+			},
+		},
+		&funcAuditor{
+			fakeAuditor: fakeAuditor{id: "test-slow"},
+			audit: func(ctx context.Context) ([]types.AuditResult, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+		&fakeAuditor{id: "test-plain"},
+	}
+
+	outcomes := Run(context.Background(), selected, types.Discovery{}, types.NewResources(), RunOptions{
+		Workers: 2,
+		Timeout: 50 * time.Millisecond,
+	})
+	assert.Equal(t, 4, len(outcomes))
+
+	assert.Equal(t, "test-ok", outcomes[0].Auditor.ID())
+	assert.Equal(t, nil, outcomes[0].Err)
+	assert.Equal(t, finding, outcomes[0].Results)
+
+	assert.Equal(t, "test-panic", outcomes[1].Auditor.ID())
+	assert.Equal(t, true, strings.Contains(outcomes[1].Err.Error(), "auditor panicked: boom"))
+
+	assert.Equal(t, "test-slow", outcomes[2].Auditor.ID())
+	assert.Equal(t, true, strings.Contains(outcomes[2].Err.Error(), "timed out"))
+
+	assert.Equal(t, "test-plain", outcomes[3].Auditor.ID())
+	assert.Equal(t, nil, outcomes[3].Err)
+}
+
+// crossAuditor reports one result per cluster when auditing them together.
+type crossAuditor struct {
+	fakeAuditor
+}
+
+func (a *crossAuditor) Audit(types.Discovery, types.Resources) ([]types.AuditResult, error) {
+	return []types.AuditResult{{Description: "single"}}, nil
+}
+
+func (a *crossAuditor) AuditClusters(clusters []types.Cluster) ([]types.AuditResult, error) {
+	var results []types.AuditResult
+	for _, cluster := range clusters {
+		results = append(results, types.AuditResult{Description: "cross", Cluster: cluster.Name})
+	}
+	return results, nil
+}
+
+func TestRunClusters(t *testing.T) {
+	selected := []types.Auditor{
+		&crossAuditor{fakeAuditor: fakeAuditor{id: "test-cross"}},
+		&fakeAuditor{id: "test-plain"},
+	}
+	clusters := []types.Cluster{
+		{Name: "east", Resources: types.NewResources()},
+		{Name: "west", Resources: types.NewResources()},
+	}
+
+	outcomes := RunClusters(context.Background(), selected, clusters, RunOptions{})
+	assert.Equal(t, 5, len(outcomes))
+
+	assert.Equal(t, "east", outcomes[0].Cluster)
+	assert.Equal(t, []types.AuditResult{{Description: "single", Cluster: "east"}}, outcomes[0].Results)
+	assert.Equal(t, "west", outcomes[2].Cluster)
+	assert.Equal(t, []types.AuditResult{{Description: "single", Cluster: "west"}}, outcomes[2].Results)
+
+	assert.Equal(t, "test-cross", outcomes[4].Auditor.ID())
+	assert.Equal(t, "", outcomes[4].Cluster)
+	assert.Equal(t, 2, len(outcomes[4].Results))
+	assert.Equal(t, "west", outcomes[4].Results[1].Cluster)
+
+	// cross-cluster audits are skipped for a single cluster
+	outcomes = RunClusters(context.Background(), selected, clusters[:1], RunOptions{})
+	assert.Equal(t, 2, len(outcomes))
+}
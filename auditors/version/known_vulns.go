@@ -18,6 +18,10 @@ package version
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
 
 	"github.com/praetorian-inc/snowcat/auditors"
 	"github.com/praetorian-inc/snowcat/pkg/knownvulns"
@@ -30,29 +34,97 @@ func init() {
 
 type auditor struct{}
 
+func (a *auditor) ID() string {
+	return "version-known-vulns"
+}
+
 func (a *auditor) Name() string {
 	return "Known Vulnerable Version"
 }
 
+// version is an istio version and the revisions that run it.
+type version struct {
+	version   string
+	revisions []string
+}
+
+// versions returns the distinct versions of the control plane revisions, or
+// the single discovered version if no revision has a known version.
+func versions(disco types.Discovery) []version {
+	var res []version
+	index := make(map[string]int)
+	for _, rev := range disco.Revisions {
+		if rev.IstioVersion == "" {
+			continue
+		}
+		i, ok := index[rev.IstioVersion]
+		if !ok {
+			i = len(res)
+			index[rev.IstioVersion] = i
+			res = append(res, version{version: rev.IstioVersion})
+		}
+		res[i].revisions = append(res[i].revisions, rev.Name)
+	}
+	if len(res) == 0 && disco.IstioVersion != "" {
+		res = append(res, version{version: disco.IstioVersion})
+	}
+	return res
+}
+
+func (a *auditor) Scope(disco types.Discovery, _ types.Resources) []string {
+	var scope []string
+	for _, v := range versions(disco) {
+		scope = append(scope, "Version "+v.version)
+	}
+	return scope
+}
+
 func (a *auditor) Audit(disco types.Discovery, _ types.Resources) ([]types.AuditResult, error) {
 	var results []types.AuditResult
 
-	if disco.IstioVersion == "" {
-		return nil, fmt.Errorf("version required")
+	found := versions(disco)
+	if len(found) == 0 {
+		// nothing to check, which is not a failure of the scan
+		log.WithFields(log.Fields{
+			"auditor": a.ID(),
+		}).Warn("istio version unknown, skipping known vulnerabilities")
+		return nil, nil
 	}
 
-	vulns, err := knownvulns.GetVulnsForVersion(disco.IstioVersion)
-	if err != nil {
-		return nil, fmt.Errorf("error retrieving vulns for version: %w", err)
-	}
+	for _, v := range found {
+		vulns, err := knownvulns.GetVulnsForVersion(v.version)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving vulns for version: %w", err)
+		}
+
+		// the revision is only named when several revisions run
+		var revision string
+		if len(disco.Revisions) > 1 {
+			revision = strings.Join(v.revisions, ",")
+		}
+
+		for _, vuln := range vulns {
+			// unparsable scores are still reported, just without a rating
+			severity := types.Unknown
+			if score, err := strconv.ParseFloat(vuln.ImpactScore, 64); err == nil {
+				severity = types.SeverityFromScore(score)
+			}
 
-	for _, vuln := range vulns {
-		results = append(results, types.AuditResult{
-			Name:     a.Name(),
-			Resource: "Version " + disco.IstioVersion,
-			Description: fmt.Sprintf("Vulnerable to %s (Impact Score %s) - more details at %s",
-				vuln.DisclosureID, vuln.ImpactScore, vuln.DisclosureURL),
-		})
+			results = append(results, types.AuditResult{
+				Name:     a.Name(),
+				Severity: severity,
+				Resource: "Version " + v.version,
+				Revision: revision,
+				ID:       vuln.DisclosureID,
+				Description: fmt.Sprintf("Vulnerable to %s (Impact Score %s) - more details at %s",
+					vuln.DisclosureID, vuln.ImpactScore, vuln.DisclosureURL),
+				Remediation: "upgrade the istio control plane and data plane to a patched release",
+				References: []string{
+					vuln.DisclosureURL,
+					knownvulns.BulletinURL,
+				},
+			})
+		}
 	}
 
 	return results, nil
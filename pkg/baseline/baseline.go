@@ -0,0 +1,173 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package baseline implements suppression of accepted audit results. A
+// baseline is a YAML file listing suppressions, each of which matches results
+// by auditor, resource and fingerprint and records why the risk was accepted:
+//
+//	suppressions:
+//	- auditor: gateway-broad-hosts
+//	  resource: istio-system:public-gateway
+//	  justification: the public gateway intentionally serves every host
+//	  expires: 2022-06-30
+//
+// Expired suppressions no longer apply, so accepted risks are revisited.
+package baseline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// DateLayout is the layout of suppression expiry dates.
+const DateLayout = "2006-01-02"
+
+// Suppression accepts the results matching all of its non-empty match fields.
+type Suppression struct {
+	// Auditor is the ID of the auditor that reported the result.
+	Auditor string `yaml:"auditor,omitempty"`
+	// Resource is the affected resource, as reported in the result.
+	Resource string `yaml:"resource,omitempty"`
+	// Fingerprint is the fingerprint of a single result.
+	Fingerprint string `yaml:"fingerprint,omitempty"`
+	// Justification records why the result is accepted and is required.
+	Justification string `yaml:"justification"`
+	// Expires is an optional date, in the form 2006-01-02, after which
+	// the suppression no longer applies.
+	Expires string `yaml:"expires,omitempty"`
+
+	expires time.Time
+}
+
+// Matches returns true if the suppression applies to the result.
+func (s *Suppression) Matches(res types.AuditResult) bool {
+	if s.Auditor != "" && s.Auditor != res.Auditor {
+		return false
+	}
+	if s.Resource != "" && s.Resource != res.Resource {
+		return false
+	}
+	if s.Fingerprint != "" && s.Fingerprint != res.Fingerprint {
+		return false
+	}
+	return true
+}
+
+// Expired returns true if the suppression has an expiry date before now.
+// Suppressions expire at the end of the day on their expiry date.
+func (s *Suppression) Expired(now time.Time) bool {
+	if s.expires.IsZero() {
+		return false
+	}
+	return !now.Before(s.expires.AddDate(0, 0, 1))
+}
+
+func (s *Suppression) String() string {
+	return fmt.Sprintf("auditor=%q resource=%q fingerprint=%q", s.Auditor, s.Resource, s.Fingerprint)
+}
+
+func (s *Suppression) validate() error {
+	if s.Auditor == "" && s.Resource == "" && s.Fingerprint == "" {
+		return fmt.Errorf("suppression must match on at least one of auditor, resource or fingerprint")
+	}
+	if s.Justification == "" {
+		return fmt.Errorf("suppression %s is missing a justification", s)
+	}
+	if s.Expires != "" {
+		expires, err := time.Parse(DateLayout, s.Expires)
+		if err != nil {
+			return fmt.Errorf("suppression %s has invalid expiry: %w", s, err)
+		}
+		s.expires = expires
+	}
+	return nil
+}
+
+// Baseline is a set of suppressions.
+type Baseline struct {
+	Suppressions []Suppression `yaml:"suppressions"`
+}
+
+// Parse decodes and validates a baseline from YAML.
+func Parse(data []byte) (*Baseline, error) {
+	var b Baseline
+	if err := yaml.UnmarshalStrict(data, &b); err != nil {
+		return nil, err
+	}
+	for i := range b.Suppressions {
+		if err := b.Suppressions[i].validate(); err != nil {
+			return nil, fmt.Errorf("suppressions[%d]: %w", i, err)
+		}
+	}
+	return &b, nil
+}
+
+// Load reads a baseline from a YAML file.
+func Load(path string) (*Baseline, error) {
+	data, err := ioutil.ReadFile(path) // nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Outcome summarizes the application of a baseline to a set of results.
+type Outcome struct {
+	// Suppressed is the number of results that were dropped.
+	Suppressed int
+	// Expired lists suppressions that are past their expiry date.
+	Expired []Suppression
+	// Unused lists unexpired suppressions that matched no result.
+	Unused []Suppression
+}
+
+// Apply returns the results that are not matched by an unexpired suppression,
+// along with a summary of which suppressions were applied.
+func (b *Baseline) Apply(results []types.AuditResult, now time.Time) ([]types.AuditResult, Outcome) {
+	var outcome Outcome
+
+	used := make([]bool, len(b.Suppressions))
+	var kept []types.AuditResult
+	for _, res := range results {
+		suppressed := false
+		for i := range b.Suppressions {
+			s := &b.Suppressions[i]
+			if s.Expired(now) || !s.Matches(res) {
+				continue
+			}
+			used[i] = true
+			suppressed = true
+		}
+		if suppressed {
+			outcome.Suppressed++
+			continue
+		}
+		kept = append(kept, res)
+	}
+
+	for i, s := range b.Suppressions {
+		switch {
+		case s.Expired(now):
+			outcome.Expired = append(outcome.Expired, s)
+		case !used[i]:
+			outcome.Unused = append(outcome.Unused, s)
+		}
+	}
+	return kept, outcome
+}
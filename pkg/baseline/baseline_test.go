@@ -0,0 +1,79 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baseline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const baselineYAML = `
+suppressions:
+- auditor: gateway-broad-hosts
+  resource: istio-system:public-gateway
+  justification: the public gateway intentionally serves every host
+- fingerprint: 0123456789abcdef
+  justification: accepted until the migration completes
+  expires: 2021-06-30
+- auditor: peerauth-permissive-mtls
+  justification: no longer applicable
+`
+
+func TestParse(t *testing.T) {
+	b, err := Parse([]byte(baselineYAML))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(b.Suppressions))
+
+	_, err = Parse([]byte("suppressions:\n- auditor: gateway-broad-hosts\n"))
+	assert.NotEqual(t, nil, err)
+
+	_, err = Parse([]byte("suppressions:\n- justification: matches everything\n"))
+	assert.NotEqual(t, nil, err)
+
+	_, err = Parse([]byte("suppressions:\n- auditor: a\n  justification: b\n  expires: soon\n"))
+	assert.NotEqual(t, nil, err)
+}
+
+func TestApply(t *testing.T) {
+	b, err := Parse([]byte(baselineYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []types.AuditResult{
+		{Auditor: "gateway-broad-hosts", Resource: "istio-system:public-gateway", Fingerprint: "aaaaaaaaaaaaaaaa"},
+		{Auditor: "gateway-broad-hosts", Resource: "default:internal", Fingerprint: "bbbbbbbbbbbbbbbb"},
+		{Auditor: "authz-deny-positive", Resource: "default:deny", Fingerprint: "0123456789abcdef"},
+	}
+
+	// the fingerprint suppression still applies on its expiry date
+	kept, outcome := b.Apply(results, time.Date(2021, 6, 30, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, 1, len(kept))
+	assert.Equal(t, "default:internal", kept[0].Resource)
+	assert.Equal(t, 2, outcome.Suppressed)
+	assert.Equal(t, 0, len(outcome.Expired))
+	assert.Equal(t, 1, len(outcome.Unused))
+	assert.Equal(t, "peerauth-permissive-mtls", outcome.Unused[0].Auditor)
+
+	kept, outcome = b.Apply(results, time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 2, len(kept))
+	assert.Equal(t, 1, outcome.Suppressed)
+	assert.Equal(t, 1, len(outcome.Expired))
+	assert.Equal(t, "0123456789abcdef", outcome.Expired[0].Fingerprint)
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/praetorian-inc/snowcat/pkg/runner"
+	"github.com/praetorian-inc/snowcat/pkg/xds"
+)
+
+// clientOptions returns the options of the clients that discovery connects
+// with: the per-client timeouts of the configuration file, and the
+// credentials of the workload when the secure xds is selected.
+func clientOptions() runner.Clients {
+	var clients runner.Clients
+	clients.XDS.Timeout = clientTimeout("timeouts.xds")
+	clients.Debugz.Timeout = clientTimeout("timeouts.debugz")
+	clients.Kubelet.Timeout = clientTimeout("timeouts.kubelet")
+	clients.Envoy.Timeout = clientTimeout("timeouts.envoy")
+
+	if viper.GetBool("secure-xds") {
+		clients.XDS.Credentials = xdsCredentials()
+	}
+	return clients
+}
+
+// clientTimeout returns the timeout configured under key, or zero for the
+// default timeout of the client.
+func clientTimeout(key string) time.Duration {
+	if !viper.IsSet(key) {
+		return 0
+	}
+	timeout := viper.GetDuration(key)
+	if timeout <= 0 {
+		log.WithFields(log.Fields{
+			"option": key,
+			"value":  viper.GetString(key),
+		}).Fatal("invalid client timeout")
+	}
+	return timeout
+}
+
+// xdsCredentials loads the credentials of the workload, so that istiod is
+// queried as an authenticated sidecar.
+func xdsCredentials() *xds.Credentials {
+	creds, err := xds.LoadCredentials(viper.GetString("root-cert"), viper.GetString("xds-token"))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Fatal("failed to load secure xds credentials")
+	}
+	log.WithFields(log.Fields{
+		"namespace": creds.Namespace,
+	}).Info("using secure xds with the workload's credentials")
+	return creds
+}
@@ -0,0 +1,199 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/praetorian-inc/snowcat/pkg/runner"
+	"github.com/praetorian-inc/snowcat/pkg/runner/istiod"
+	"github.com/praetorian-inc/snowcat/pkg/runner/kubelet"
+	"github.com/praetorian-inc/snowcat/pkg/runner/namespace"
+	"github.com/praetorian-inc/snowcat/pkg/runner/rbac"
+	"github.com/praetorian-inc/snowcat/pkg/snapshot"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// clusterInput is an input of a scan and the name of the cluster it holds.
+type clusterInput struct {
+	name string
+	path string
+}
+
+// parseInputs returns the inputs given as arguments. Each argument is a path,
+// optionally prefixed with "name=" to name its cluster. Unnamed inputs are
+// named after the base name of their path up to the first dot, except for a
+// single input, whose cluster is left unnamed.
+func parseInputs(args []string) ([]clusterInput, error) {
+	var inputs []clusterInput
+	names := make(map[string]bool)
+	for _, arg := range args {
+		in := clusterInput{path: arg}
+		if i := strings.Index(arg, "="); i > 0 && !strings.ContainsRune(arg[:i], os.PathSeparator) {
+			if _, err := os.Stat(arg); err != nil {
+				in = clusterInput{name: arg[:i], path: arg[i+1:]}
+			}
+		}
+		if in.name == "" && len(args) > 1 {
+			in.name = defaultClusterName(in.path)
+		}
+
+		if in.path != types.Stdin {
+			if _, err := os.Stat(in.path); err != nil {
+				return nil, fmt.Errorf("invalid input: %s", err)
+			}
+		}
+		if names[in.name] {
+			return nil, fmt.Errorf("cluster %q is given more than once, name the inputs with name=path", in.name)
+		}
+		names[in.name] = true
+		inputs = append(inputs, in)
+	}
+	return inputs, nil
+}
+
+// defaultClusterName returns the name of the cluster of an unnamed input.
+func defaultClusterName(path string) string {
+	if path == types.Stdin {
+		return "stdin"
+	}
+	name := filepath.Base(filepath.Clean(path))
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// clusterPath returns the path that the output of the named cluster is written
+// to when several clusters are scanned, inserting the name before the first
+// dot of the base name of path.
+func clusterPath(path, name string, clusters int) string {
+	if clusters < 2 {
+		return path
+	}
+	dir, base := filepath.Split(path)
+	if i := strings.Index(base, "."); i > 0 {
+		return dir + base[:i] + "-" + name + base[i:]
+	}
+	return path + "-" + name
+}
+
+// loadInput loads the cluster held by an input, which is either a snapshot
+// bundle or manifests.
+func loadInput(cmd *cobra.Command, in clusterInput) (types.Cluster, snapshot.Manifest) {
+	cluster := types.Cluster{
+		Name:      in.name,
+		Discovery: buildInitialDiscovery(),
+		Resources: types.NewResources(),
+	}
+
+	if snap := readSnapshot(in.path); snap != nil {
+		cluster.Discovery = snapshotDiscovery(cmd, snap.Manifest.Discovery, cluster.Discovery)
+		cluster.Resources = snap.Resources
+		return cluster, snap.Manifest
+	}
+
+	start := time.Now()
+	err := cluster.Resources.LoadFromPath(in.path)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"input": in.path,
+			"err":   err,
+		}).Fatalf("failed to load resources")
+	}
+	return cluster, collectionManifest(in.path, start, time.Now())
+}
+
+// loadLive discovers and collects the cluster that snowcat runs in, using the
+// kubeconfig file if one is given, or else the in-cluster service account, for
+// the kubernetes api.
+func loadLive(ctx context.Context, clients runner.Clients) (types.Cluster, snapshot.Manifest) {
+	cluster := types.Cluster{
+		Discovery: buildInitialDiscovery(),
+		Resources: types.NewResources(),
+	}
+
+	start := time.Now()
+	// the api is collected first, so that the istiod of every revision it
+	// names is probed for, not only those of the configured revisions
+	if err := collectFromAPI(ctx, "", &cluster.Discovery, &cluster.Resources); err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Warn("skipping api collection")
+	}
+	addResourceRevisions(&cluster.Discovery, cluster.Resources)
+
+	// runners declare the discovery fields they require and provide, so
+	// the order of this list does not matter
+	runners := runner.Runners{
+		kubelet.Runner,
+		namespace.Runner,
+		istiod.Runner,
+		rbac.Runner,
+	}
+	runners.Run(ctx, clients, &cluster.Discovery, &cluster.Resources)
+	return cluster, collectionManifest("", start, time.Now())
+}
+
+// addResourceRevisions names the control plane revisions found among the
+// resources in disco, so that discovery looks for their istiod.
+func addResourceRevisions(disco *types.Discovery, resources types.Resources) {
+	names := types.ResourceRevisions(resources)
+	if len(names) == 0 {
+		return
+	}
+	for _, name := range names {
+		disco.AddRevision(types.Revision{Name: name})
+	}
+	if disco.Provenance == nil {
+		disco.Provenance = &types.Provenance{Started: time.Now()}
+	}
+	disco.Provenance.SetField(string(runner.Revisions), types.SourceKubernetesAPI, time.Now())
+}
+
+// loadContext collects the named cluster from the kubernetes api of a
+// kubeconfig context. Network discovery is skipped, as the addresses of
+// istiod and the kubelets are only reachable from within the cluster, not
+// from wherever the kubeconfig is used.
+func loadContext(ctx context.Context, name, kubeContext string) (types.Cluster, snapshot.Manifest) {
+	cluster := types.Cluster{
+		Name: name,
+		Discovery: types.Discovery{
+			IstioNamespace: viper.GetString("istio-namespace"),
+		},
+		Resources: types.NewResources(),
+	}
+	runner.RecordSet(&cluster.Discovery, types.SourceConfiguration)
+
+	start := time.Now()
+	// the api is the only source of a context, and auditing the context
+	// without it would report a cluster free of issues
+	if err := collectFromAPI(ctx, kubeContext, &cluster.Discovery, &cluster.Resources); err != nil {
+		log.WithFields(log.Fields{
+			"context": kubeContext,
+			"err":     err,
+		}).Fatal("failed to collect context")
+	}
+	return cluster, collectionManifest("", start, time.Now())
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestParseInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"prod.yaml", "staging.yaml", "a=b.yaml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prod := filepath.Join(dir, "prod.yaml")
+	staging := filepath.Join(dir, "staging.yaml")
+	literal := filepath.Join(dir, "a=b.yaml")
+
+	type testcase struct {
+		description string
+		args        []string
+		inputs      []clusterInput
+		fails       bool
+	}
+
+	testcases := []testcase{
+		{"single input is unnamed", []string{prod}, []clusterInput{{path: prod}}, false},
+		{"single named input", []string{"east=" + prod}, []clusterInput{{name: "east", path: prod}}, false},
+		{"unnamed inputs are named after their files", []string{prod, staging},
+			[]clusterInput{{name: "prod", path: prod}, {name: "staging", path: staging}}, false},
+		{"named and unnamed inputs", []string{"east=" + prod, staging},
+			[]clusterInput{{name: "east", path: prod}, {name: "staging", path: staging}}, false},
+		{"existing path with an equals sign", []string{literal}, []clusterInput{{path: literal}}, false},
+		{"standard input", []string{"-"}, []clusterInput{{path: "-"}}, false},
+		{"duplicate default names", []string{prod, prod}, nil, true},
+		{"duplicate given names", []string{"east=" + prod, "east=" + staging}, nil, true},
+		{"missing path", []string{filepath.Join(dir, "missing.yaml")}, nil, true},
+		{"missing named path", []string{"east=" + filepath.Join(dir, "missing.yaml")}, nil, true},
+	}
+
+	for _, tc := range testcases {
+		inputs, err := parseInputs(tc.args)
+		assert.Equal(t, tc.fails, err != nil, tc.description)
+		assert.Equal(t, tc.inputs, inputs, tc.description)
+	}
+}
+
+func TestClusterPath(t *testing.T) {
+	type testcase struct {
+		description string
+		path        string
+		clusters    int
+		expected    string
+	}
+
+	testcases := []testcase{
+		{"single cluster", "out/report.json", 1, "out/report.json"},
+		{"name before the extension", "out/report.json", 2, "out/report-east.json"},
+		{"name before the first dot", "out/report.sarif.json", 2, "out/report-east.sarif.json"},
+		{"dot in the directory", "out.d/report", 2, "out.d/report-east"},
+		{"no extension", "report", 2, "report-east"},
+		{"hidden file", ".report", 2, ".report-east"},
+	}
+
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expected, clusterPath(tc.path, "east", tc.clusters), tc.description)
+	}
+}
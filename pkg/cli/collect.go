@@ -0,0 +1,88 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/rest"
+
+	"github.com/praetorian-inc/snowcat/pkg/runner/kubeapi"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// collectFromAPI lists resources from the kubernetes api server and records
+// the collection in the provenance of disco. The api is only used when asked
+// for, with a kubeconfig file or a context given, or else with the in-cluster
+// service account, so that a scan never collects from whatever cluster the
+// current kubeconfig context happens to point at. An empty kubeContext selects
+// the current context of the kubeconfig file. The returned error is set if the
+// api server cannot be reached with the chosen credentials.
+func collectFromAPI(ctx context.Context, kubeContext string, disco *types.Discovery, resources *types.Resources) error {
+	kubeconfig := viper.GetString("kubeconfig")
+	var config *rest.Config
+	var err error
+	if kubeconfig == "" && kubeContext == "" {
+		config, err = kubeapi.InClusterConfig()
+		if errors.Is(err, rest.ErrNotInCluster) {
+			log.Info("not running in a cluster and no kubeconfig or context given, skipping api collection")
+			return nil
+		}
+	} else {
+		config, err = kubeapi.Config(kubeconfig, kubeContext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load kubernetes credentials: %w", err)
+	}
+
+	collector, err := kubeapi.NewCollector(config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize kubernetes client: %w", err)
+	}
+
+	log.WithFields(log.Fields{
+		"host":    config.Host,
+		"context": kubeContext,
+	}).Info("collecting resources from kubernetes api")
+
+	report, err := collector.Collect(ctx, resources)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Warn("failed to collect some kinds from kubernetes api")
+	}
+	if len(report.Forbidden) > 0 {
+		log.WithFields(log.Fields{
+			"kinds": strings.Join(report.Forbidden, ","),
+		}).Warn("forbidden from listing some kinds, results may be incomplete")
+	}
+
+	if disco.Provenance == nil {
+		disco.Provenance = &types.Provenance{Started: time.Now()}
+	}
+	disco.Provenance.Collection = &types.Collection{
+		Host:      config.Host,
+		Context:   kubeContext,
+		Forbidden: report.Forbidden,
+		Missing:   report.Missing,
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/praetorian-inc/snowcat/pkg/diff"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+var (
+	diffFormatFlag string
+	diffOutputFlag string
+)
+
+// diffCmd compares two scans of the same target
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "compare two scans and report new, resolved and persisting results",
+	Long: `compare two scans of the same target. each input is either a results
+file written with --format json, or resources written with --export as a
+directory, archive or manifest file, in which case the auditors are run
+against them first. files and standard input (-) are recognized by their
+content. results are matched by fingerprint, so rewording a finding does not
+affect the comparison`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunDiff(args[0], args[1])
+	},
+}
+
+// diffFormats are the values accepted by diff --format.
+var diffFormats = []string{"json", "text"}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFormatFlag, "format", "text", "output format [json, text]")
+	diffCmd.Flags().StringVar(&diffOutputFlag, "output", "", "write the comparison to the specified file")
+
+	rootCmd.AddCommand(diffCmd)
+}
+
+// loadScan returns the results of a scan, auditing the input first if it is
+// a snapshot bundle or manifests rather than a results file. The audit is not
+// interruptible, as partial results would show up as resolved.
+func loadScan(path string) []types.AuditResult {
+	cluster, results, err := readScan(path)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"input": path,
+			"err":   err,
+		}).Fatal("invalid input")
+	}
+	if cluster == nil {
+		return filterResults(results)
+	}
+	cluster.Resources.LoadMeshConfigMap(cluster.Discovery)
+	results, failed := runAuditors(context.Background(), selectAuditors(), []types.Cluster{*cluster})
+	if err := scanFailure(results, nil, failed); err != nil {
+		// the issues of the failed auditors would show up as resolved
+		log.WithFields(log.Fields{
+			"input": path,
+			"err":   err,
+		}).Fatal("failed to audit input")
+	}
+	return filterResults(results)
+}
+
+// readScan reads the input of a diff. Files and standard input are detected
+// from their content: results written with --format json are returned as is,
+// while snapshot bundles and manifests are returned as a cluster to audit.
+func readScan(path string) (*types.Cluster, []types.AuditResult, error) {
+	var data []byte
+	if path == types.Stdin {
+		var err error
+		if data, err = io.ReadAll(os.Stdin); err != nil {
+			return nil, nil, err
+		}
+		if results, err := diff.ParseResults("stdin", data); err == nil {
+			return nil, results, nil
+		}
+	} else {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if stat.Mode().IsRegular() {
+			results, err := diff.LoadResults(path)
+			if err == nil {
+				return nil, results, nil
+			}
+			// other files may still hold manifests or a snapshot
+			log.WithFields(log.Fields{
+				"input": path,
+				"err":   err,
+			}).Debug("input is not a results file")
+		}
+	}
+
+	if snap := readSnapshot(path); snap != nil {
+		return &types.Cluster{
+			Discovery: snap.Manifest.Discovery,
+			Resources: snap.Resources,
+		}, nil, nil
+	}
+
+	resources := types.NewResources()
+	var err error
+	if data != nil {
+		err = resources.LoadFromReader("stdin", bytes.NewReader(data))
+	} else {
+		err = resources.LoadFromPath(path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if resources.Len() == 0 {
+		return nil, nil, errors.New("input holds neither results nor resources")
+	}
+	return &types.Cluster{
+		Discovery: buildInitialDiscovery(),
+		Resources: resources,
+	}, nil, nil
+}
+
+// RunDiff compares the scans at oldPath and newPath.
+func RunDiff(oldPath, newPath string) {
+	if err := checkFormat(diffFormatFlag, diffFormats); err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Fatal("invalid output format")
+	}
+	d := diff.Compare(loadScan(oldPath), loadScan(newPath))
+
+	out := createOutput(diffOutputFlag)
+	defer out.Close()
+
+	switch diffFormatFlag {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(d)
+	case "text":
+		bold := color.New(color.Bold).SprintFunc()
+		sections := []struct {
+			title   string
+			results []types.AuditResult
+		}{
+			{"new", d.New},
+			{"resolved", d.Resolved},
+			{"persisting", d.Persisting},
+		}
+		for _, section := range sections {
+			fmt.Fprintf(out, "%s (%d)\n", bold(section.title), len(section.results))
+			writeText(out, section.results)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/report"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const gatewayManifest = `
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: ingress
+  namespace: istio-system
+spec:
+  servers:
+  - port:
+      number: 80
+      name: http
+      protocol: HTTP
+    hosts:
+    - "*"
+`
+
+func TestReadScan(t *testing.T) {
+	scanned := []types.AuditResult{
+		{Auditor: "gateway-broad-hosts", Kind: "Gateway", Resource: "istio-system:ingress", Severity: types.High},
+	}
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf, scanned, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	manifests := filepath.Join(dir, "manifests")
+	if err := os.Mkdir(manifests, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"results":                buf.String(),
+		"results.yaml":           buf.String(),
+		"gateway":                gatewayManifest,
+		"manifests/gateway.yaml": gatewayManifest,
+		"empty.json":             "",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type testcase struct {
+		description string
+		path        string
+		stdin       string
+		results     []types.AuditResult
+		gateways    int
+		fails       bool
+	}
+
+	testcases := []testcase{
+		{"results without an extension", "results", "", scanned, 0, false},
+		{"results with another extension", "results.yaml", "", scanned, 0, false},
+		{"results on standard input", types.Stdin, buf.String(), scanned, 0, false},
+		{"manifest without an extension", "gateway", "", nil, 1, false},
+		{"directory of manifests", "manifests", "", nil, 1, false},
+		{"manifest on standard input", types.Stdin, gatewayManifest, nil, 1, false},
+		{"empty file", "empty.json", "", nil, 0, true},
+		{"empty standard input", types.Stdin, "", nil, 0, true},
+		{"missing file", "missing.json", "", nil, 0, true},
+	}
+
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+	for _, tc := range testcases {
+		path := tc.path
+		if path == types.Stdin {
+			f, err := os.CreateTemp(dir, "stdin")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err := f.WriteString(tc.stdin); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.Seek(0, 0); err != nil {
+				t.Fatal(err)
+			}
+			os.Stdin = f
+		} else {
+			path = filepath.Join(dir, path)
+		}
+
+		cluster, results, err := readScan(path)
+		assert.Equal(t, tc.fails, err != nil, tc.description)
+		assert.Equal(t, tc.results, results, tc.description)
+		gateways := 0
+		if cluster != nil {
+			gateways = len(cluster.Resources.Gateways)
+		}
+		assert.Equal(t, tc.gateways, gateways, tc.description)
+	}
+}
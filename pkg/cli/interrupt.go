@@ -0,0 +1,54 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// interruptContext returns a context that is canceled on the first interrupt
+// or termination signal. Signals are only caught once, so a second one stops
+// snowcat immediately.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// collectionContext returns the context that bounds discovery and collection,
+// which is canceled with interrupted or after the timeout, if any.
+func collectionContext(interrupted context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(interrupted)
+	}
+	return context.WithTimeout(interrupted, timeout)
+}
+
+// auditContext returns the context that the auditors run with. An interrupt
+// cancels the auditors, unless it already stopped collection, in which case
+// the collected resources are audited in full.
+func auditContext(interrupted context.Context) context.Context {
+	if interrupted.Err() != nil {
+		return context.Background()
+	}
+	return interrupted
+}
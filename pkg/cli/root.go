@@ -15,11 +15,12 @@
 package cli
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -33,14 +34,19 @@ import (
 	_ "github.com/praetorian-inc/snowcat/auditors/authz"
 	_ "github.com/praetorian-inc/snowcat/auditors/destinationrule"
 	_ "github.com/praetorian-inc/snowcat/auditors/gateway"
+	_ "github.com/praetorian-inc/snowcat/auditors/gatewayapi"
 	_ "github.com/praetorian-inc/snowcat/auditors/install"
+	_ "github.com/praetorian-inc/snowcat/auditors/mesh"
+	_ "github.com/praetorian-inc/snowcat/auditors/multicluster"
 	_ "github.com/praetorian-inc/snowcat/auditors/peerauth"
+	_ "github.com/praetorian-inc/snowcat/auditors/rbac"
 	_ "github.com/praetorian-inc/snowcat/auditors/version"
+	"github.com/praetorian-inc/snowcat/pkg/baseline"
+	"github.com/praetorian-inc/snowcat/pkg/report"
 	"github.com/praetorian-inc/snowcat/pkg/runner"
-	"github.com/praetorian-inc/snowcat/pkg/runner/istiod"
-	"github.com/praetorian-inc/snowcat/pkg/runner/kubelet"
-	"github.com/praetorian-inc/snowcat/pkg/runner/namespace"
+	"github.com/praetorian-inc/snowcat/pkg/snapshot"
 	"github.com/praetorian-inc/snowcat/pkg/types"
+	"github.com/praetorian-inc/snowcat/pkg/xds"
 )
 
 var (
@@ -48,12 +54,28 @@ var (
 	logLevelFlag         string
 	formatFlag           string
 	exportDirectoryFlag  string
+	snapshotFileFlag     string
 	outputFileFlag       string
+	minSeverityFlag      string
+	failOnFlag           string
+	enableFlag           []string
+	disableFlag          []string
+	baselineFlag         string
+	parallelismFlag      int
+	auditorTimeoutFlag   time.Duration
+	timeoutFlag          time.Duration
 	istioVersionFlag     string
 	istioNamespaceFlag   string
 	discoveryAddressFlag string
 	debugzAddressFlag    string
 	kubeletAddressesFlag []string
+	revisionsFlag        []string
+	secureXDSFlag        bool
+	rootCertFlag         string
+	xdsTokenFlag         string
+	meshConfigFlag       string
+	kubeconfigFlag       string
+	kubeContextsFlag     []string
 	saveConfFlag         bool
 	jobMode              bool
 )
@@ -67,52 +89,75 @@ kubectl -n %s cp %s:%s snowcat-results
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
-	Use:   "snowcat [input]",
+	Use:   "snowcat [input...]",
 	Short: "an istio security scanner",
 	Long: `this tool can be used by an organization looking to audit their own
 istio service mesh, or by a security engineer looking to evaluate a customer's mesh.
 it is capable of operating in a few different modes, including configuration files
-and live clusters`,
+and live clusters. the input may be a directory, a .tar, .tar.gz or .zip archive,
+a single manifest file, a snapshot bundle, or - to read manifests from stdin.
+several inputs, optionally named as name=path, are scanned as separate clusters`,
 	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) > 1 {
-			return errors.New("too many arguments specified")
-		}
-		if len(args) == 0 {
-			return nil
-		}
-
-		path := args[0]
-		stat, err := os.Stat(path)
-		if err != nil {
-			return fmt.Errorf("invalid input directory: %s", err)
-		}
-		if !stat.IsDir() {
-			return fmt.Errorf("invalid input: %s must be a directory", path)
-		}
-		return nil
+		_, err := parseInputs(args)
+		return err
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		RunSnowcat(args)
+		RunSnowcat(cmd, args)
 	},
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&configFileFlag, "config", "c", "snowcat.yml",
+	rootCmd.PersistentFlags().StringVarP(&configFileFlag, "config", "c", "snowcat.yml",
 		"snowcat configuration file")
-	rootCmd.Flags().StringVarP(&logLevelFlag, "log-level", "l", "info",
+	rootCmd.PersistentFlags().StringVarP(&logLevelFlag, "log-level", "l", "info",
 		"log level, see https://github.com/sirupsen/logrus#level-logging for options.")
-	viper.BindPFlag("log-level", rootCmd.Flags().Lookup("log-level"))
+	viper.BindPFlag("log-level", rootCmd.PersistentFlags().Lookup("log-level"))
 
 	cobra.OnInitialize(initConfig)
 
-	rootCmd.Flags().StringVar(&formatFlag, "format", "text", "output format [json, text]")
+	rootCmd.Flags().StringVar(&formatFlag, "format", "text", "output format [html, json, junit, sarif, text]")
 
 	rootCmd.Flags().StringVar(&exportDirectoryFlag, "export", "",
 		"write discovered resources to the specified export directory as yaml")
 
+	rootCmd.Flags().StringVar(&snapshotFileFlag, "snapshot", "",
+		"write discovery and resources to the specified snapshot bundle")
+
 	rootCmd.Flags().StringVar(&outputFileFlag, "output", "",
 		"write results to the specified file")
 
+	rootCmd.PersistentFlags().StringVar(&minSeverityFlag, "min-severity", "",
+		"only report results at or above this severity [none, low, medium, high, critical]")
+	viper.BindPFlag("min-severity", rootCmd.PersistentFlags().Lookup("min-severity"))
+
+	rootCmd.Flags().StringVar(&failOnFlag, "fail-on", "",
+		"exit non-zero if a result at or above this severity is reported")
+	viper.BindPFlag("fail-on", rootCmd.Flags().Lookup("fail-on"))
+
+	rootCmd.PersistentFlags().StringSliceVar(&enableFlag, "enable", []string{},
+		"only run the auditors matching these IDs or categories")
+	viper.BindPFlag("enable", rootCmd.PersistentFlags().Lookup("enable"))
+
+	rootCmd.PersistentFlags().StringSliceVar(&disableFlag, "disable", []string{},
+		"skip the auditors matching these IDs or categories")
+	viper.BindPFlag("disable", rootCmd.PersistentFlags().Lookup("disable"))
+
+	rootCmd.PersistentFlags().StringVar(&baselineFlag, "baseline", "",
+		"suppress accepted results listed in the specified baseline file")
+	viper.BindPFlag("baseline", rootCmd.PersistentFlags().Lookup("baseline"))
+
+	rootCmd.PersistentFlags().IntVar(&parallelismFlag, "parallelism", runtime.NumCPU(),
+		"maximum number of auditors to run concurrently")
+	viper.BindPFlag("parallelism", rootCmd.PersistentFlags().Lookup("parallelism"))
+
+	rootCmd.PersistentFlags().DurationVar(&auditorTimeoutFlag, "auditor-timeout", time.Minute,
+		"maximum run time of each auditor, 0 disables the timeout")
+	viper.BindPFlag("auditor-timeout", rootCmd.PersistentFlags().Lookup("auditor-timeout"))
+
+	rootCmd.Flags().DurationVar(&timeoutFlag, "timeout", 0,
+		"maximum duration of discovery and collection, after which the collected resources are audited, 0 disables the timeout")
+	viper.BindPFlag("timeout", rootCmd.Flags().Lookup("timeout"))
+
 	rootCmd.Flags().StringVar(&istioVersionFlag, "istio-version", "",
 		"the version of the istio control plane")
 	viper.BindPFlag("istio-version", rootCmd.Flags().Lookup("istio-version"))
@@ -133,6 +178,34 @@ func init() {
 		"list of addresses in form host:port of each node's kubelet read-only api")
 	viper.BindPFlag("kubelet-addresses", rootCmd.Flags().Lookup("kubelet-addresses"))
 
+	rootCmd.Flags().StringSliceVar(&revisionsFlag, "revisions", []string{},
+		"names of istio control plane revisions to locate in addition to the default one")
+	viper.BindPFlag("revisions", rootCmd.Flags().Lookup("revisions"))
+
+	rootCmd.Flags().BoolVar(&secureXDSFlag, "secure-xds", false,
+		"query istiod's authenticated xds on port 15012 over tls with the workload's credentials, as a sidecar does")
+	viper.BindPFlag("secure-xds", rootCmd.Flags().Lookup("secure-xds"))
+
+	rootCmd.Flags().StringVar(&rootCertFlag, "root-cert", xds.DefaultRootCertPath,
+		"root certificate of the mesh that istiod's certificate is verified against on the secure xds")
+	viper.BindPFlag("root-cert", rootCmd.Flags().Lookup("root-cert"))
+
+	rootCmd.Flags().StringVar(&xdsTokenFlag, "xds-token", "",
+		"service account token sent to the secure xds, defaults to the mounted istio or service account token")
+	viper.BindPFlag("xds-token", rootCmd.Flags().Lookup("xds-token"))
+
+	rootCmd.Flags().StringVar(&kubeconfigFlag, "kubeconfig", "",
+		"kubeconfig file used to collect resources from the kubernetes api")
+	viper.BindPFlag("kubeconfig", rootCmd.Flags().Lookup("kubeconfig"))
+
+	rootCmd.Flags().StringSliceVar(&kubeContextsFlag, "context", []string{},
+		"kubeconfig contexts used to collect resources from the kubernetes api, each one a separate cluster")
+	viper.BindPFlag("context", rootCmd.Flags().Lookup("context"))
+
+	rootCmd.Flags().StringVar(&meshConfigFlag, "mesh-config", "",
+		"file holding the mesh config, or the istio ConfigMap, used in place of the discovered one")
+	viper.BindPFlag("mesh-config", rootCmd.Flags().Lookup("mesh-config"))
+
 	rootCmd.Flags().BoolVarP(&saveConfFlag, "save-config", "s", false,
 		"whether or not to save discovery to current config file")
 
@@ -192,13 +265,18 @@ func Execute() {
 }
 
 func buildInitialDiscovery() types.Discovery {
-	return types.Discovery{
+	disco := types.Discovery{
 		IstioVersion:     viper.GetString("istio-version"),
 		IstioNamespace:   viper.GetString("istio-namespace"),
 		DiscoveryAddress: viper.GetString("discovery-address"),
 		DebugzAddress:    viper.GetString("debugz-address"),
 		KubeletAddresses: viper.GetStringSlice("kubelet-addresses"),
 	}
+	for _, name := range viper.GetStringSlice("revisions") {
+		disco.AddRevision(types.Revision{Name: name})
+	}
+	runner.RecordSet(&disco, types.SourceConfiguration)
+	return disco
 }
 
 func saveFinalDiscovery(disco types.Discovery) {
@@ -207,95 +285,473 @@ func saveFinalDiscovery(disco types.Discovery) {
 	viper.Set("discovery-address", disco.DiscoveryAddress)
 	viper.Set("debugz-address", disco.DebugzAddress)
 	viper.Set("kubelet-addresses", disco.KubeletAddresses)
+
+	var revisions []string
+	for _, rev := range disco.Revisions {
+		if rev.Name != types.DefaultRevision {
+			revisions = append(revisions, rev.Name)
+		}
+	}
+	viper.Set("revisions", revisions)
 }
 
-// RunSnowcat runs the scanner.
-func RunSnowcat(args []string) {
-	var err error
+// formats are the values accepted by --format.
+var formats = []string{"html", "json", "junit", "sarif", "text"}
 
-	var inputPath string
-	if len(args) == 1 {
-		inputPath = args[0]
+// checkFormat returns an error if format is not one of the accepted formats.
+func checkFormat(format string, accepted []string) error {
+	for _, f := range accepted {
+		if f == format {
+			return nil
+		}
 	}
+	return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(accepted, ", "))
+}
 
-	disco := buildInitialDiscovery()
-	resources := types.NewResources()
+func parseSeverityOption(name string) types.Severity {
+	value := viper.GetString(name)
+	if value == "" {
+		return types.Unknown
+	}
+	severity, err := types.ParseSeverity(value)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"option": name,
+			"err":    err,
+		}).Fatal("invalid severity")
+	}
+	return severity
+}
 
-	if inputPath == "" {
-		// Runners are executed in a specific order to resolve dependencies
-		// correctly. Reordering this list may result in failed discovery.
-		runners := runner.Runners{
-			kubelet.Runner,
-			namespace.Runner,
-			istiod.Runner,
+func filterBySeverity(results []types.AuditResult, min types.Severity) []types.AuditResult {
+	var filtered []types.AuditResult
+	for _, res := range results {
+		if res.Severity >= min {
+			filtered = append(filtered, res)
 		}
-		runners.Run(&disco, &resources)
-	} else {
-		err = resources.LoadFromDirectory(inputPath)
-		if err != nil {
+	}
+	return filtered
+}
+
+// applyBaseline drops the results suppressed by the baseline file at path and
+// reports on the suppressions that were not applied.
+func applyBaseline(path string, results []types.AuditResult) []types.AuditResult {
+	b, err := baseline.Load(path)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"baseline": path,
+			"err":      err,
+		}).Fatal("failed to load baseline")
+	}
+
+	results, outcome := b.Apply(results, time.Now())
+
+	for _, s := range outcome.Expired {
+		log.WithFields(log.Fields{
+			"auditor":     s.Auditor,
+			"resource":    s.Resource,
+			"fingerprint": s.Fingerprint,
+			"expires":     s.Expires,
+		}).Warn("baseline suppression has expired")
+	}
+	for _, s := range outcome.Unused {
+		log.WithFields(log.Fields{
+			"auditor":     s.Auditor,
+			"resource":    s.Resource,
+			"fingerprint": s.Fingerprint,
+		}).Warn("baseline suppression did not match any results")
+	}
+	log.WithFields(log.Fields{
+		"baseline":   path,
+		"suppressed": outcome.Suppressed,
+	}).Info("applied baseline")
+
+	return results
+}
+
+// sortResults orders results by cluster, auditor and resource so that reports from
+// separate runs over the same input can be compared directly.
+func sortResults(results []types.AuditResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Auditor != b.Auditor {
+			return a.Auditor < b.Auditor
+		}
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return a.Description < b.Description
+	})
+}
+
+// selectAuditors returns the auditors chosen by the enable and disable options.
+func selectAuditors() []types.Auditor {
+	selected, err := auditors.Select(viper.GetStringSlice("enable"), viper.GetStringSlice("disable"))
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Fatal("invalid auditor selection")
+	}
+	return selected
+}
+
+// runAuditors runs each auditor against the clusters and returns their
+// combined results in a deterministic order, along with the number of runs
+// that failed. Once ctx is done, the auditors that are still running are
+// canceled and the results are partial.
+func runAuditors(ctx context.Context, selected []types.Auditor, clusters []types.Cluster) ([]types.AuditResult, int) {
+	opts := auditors.RunOptions{
+		Workers: viper.GetInt("parallelism"),
+		Timeout: viper.GetDuration("auditor-timeout"),
+	}
+	log.WithFields(log.Fields{
+		"auditors":    len(selected),
+		"parallelism": opts.Workers,
+	}).Info("running auditors")
+
+	// results are attributed to revisions when several run in a cluster
+	revisioned := make(map[string]bool)
+	for _, cluster := range clusters {
+		revisioned[cluster.Name] = len(types.Revisions(cluster.Discovery, cluster.Resources)) > 1
+	}
+
+	var results []types.AuditResult
+	failed := 0
+	for _, outcome := range auditors.RunClusters(ctx, selected, clusters, opts) {
+		id := outcome.Auditor.ID()
+		if outcome.Err != nil {
+			failed++
 			log.WithFields(log.Fields{
-				"err": err,
-			}).Fatalf("failed to load resources")
+				"auditor": id,
+				"cluster": outcome.Cluster,
+				"err":     outcome.Err,
+			}).Error("auditor failed to run")
+		}
+		log.WithFields(log.Fields{
+			"auditor":  id,
+			"cluster":  outcome.Cluster,
+			"results":  len(outcome.Results),
+			"duration": outcome.Duration,
+		}).Debug("auditor finished")
+
+		res := outcome.Results
+		for i := range res {
+			res[i].Auditor = id
+			res[i].Fingerprint = types.Fingerprint(res[i])
+			cluster := types.FindCluster(clusters, res[i].Cluster)
+			if cluster == nil {
+				continue
+			}
+			if res[i].Revision == "" && revisioned[cluster.Name] {
+				res[i].Revision = cluster.Resources.NamespaceRevision(res[i].Namespace())
+			}
+			if sources := cluster.Resources.Sources(res[i].APIVersion, res[i].Kind, res[i].Resource); len(sources) > 0 {
+				res[i].Source = sources[0].String()
+			}
+		}
+		results = append(results, res...)
+	}
+	if ctx.Err() != nil {
+		log.WithFields(log.Fields{
+			"err": ctx.Err(),
+		}).Warn("auditing stopped early, results are partial")
+	}
+	sortResults(results)
+	return results, failed
+}
+
+// suppressResults drops the results suppressed by the baseline, if any.
+func suppressResults(results []types.AuditResult) []types.AuditResult {
+	if path := viper.GetString("baseline"); path != "" {
+		results = applyBaseline(path, results)
+	}
+	return results
+}
+
+// filterResults drops results suppressed by the baseline or below the
+// minimum severity.
+func filterResults(results []types.AuditResult) []types.AuditResult {
+	return filterBySeverity(suppressResults(results), parseSeverityOption("min-severity"))
+}
+
+// scanFailure returns why the scan must exit non-zero, or nil. Auditors that
+// failed to run fail the scan, as their results are missing. With failOn set,
+// so do results at or above it, which are expected to have been suppressed by
+// the baseline but not filtered by the minimum severity, since that only
+// controls what is displayed.
+func scanFailure(results []types.AuditResult, failOn *types.Severity, failed int) error {
+	if failed > 0 {
+		return fmt.Errorf("%d auditor runs failed, results may be missing", failed)
+	}
+	if failOn == nil {
+		return nil
+	}
+	if failing := filterBySeverity(results, *failOn); len(failing) > 0 {
+		return fmt.Errorf("found %d results at or above the %s fail-on severity", len(failing), failOn)
+	}
+	return nil
+}
+
+// createOutput returns the file at path, or stdout if path is empty.
+func createOutput(path string) io.WriteCloser {
+	if path == "" {
+		return os.Stdout
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"output": path,
+			"err":    err,
+		}).Fatal("failed to create output file")
+	}
+	return out
+}
+
+// writeText writes the results in human readable form. Results of several
+// clusters are grouped under a header per cluster.
+func writeText(out io.Writer, results []types.AuditResult) {
+	bold := color.New(color.Bold).SprintFunc()
+	red := color.New(color.FgRed).SprintFunc()
+	yellow := color.New(color.FgYellow).SprintFunc()
+
+	counts := make(map[string]int)
+	for _, res := range results {
+		counts[res.Cluster]++
+	}
+	for i, res := range results {
+		if res.Cluster != "" && (i == 0 || results[i-1].Cluster != res.Cluster) {
+			fmt.Fprintf(out, "%s (%d)\n", bold(res.Cluster), counts[res.Cluster])
 		}
+		resource := res.Resource
+		if res.Path != "" {
+			resource += " " + res.Path
+		}
+		if res.Source != "" {
+			resource += " " + res.Source
+		}
+		if res.Revision != "" {
+			resource += " revision=" + res.Revision
+		}
+		fmt.Fprintf(out, "%s %s [%s]: %s\n", res.Severity, red(res.Name), yellow(resource), res.Description)
+	}
+}
+
+// writeDiscovery summarizes the discovery of each cluster: the value of each
+// field and what set it, followed by the strategies that failed.
+func writeDiscovery(out io.Writer, clusters []types.Cluster) {
+	bold := color.New(color.Bold).SprintFunc()
+	faint := color.New(color.Faint).SprintFunc()
+
+	for _, cluster := range clusters {
+		disco := cluster.Discovery
+		title := "discovery"
+		if cluster.Name != "" {
+			title += " " + cluster.Name
+		}
+		fmt.Fprintln(out, bold(title))
+		for _, field := range runner.Fields {
+			value := field.Format(&disco)
+			if value == "" {
+				value = "-"
+			}
+			source := "not discovered"
+			if disco.Provenance != nil {
+				if fs := disco.Provenance.Field(string(field)); fs != nil {
+					source = fs.Source
+				}
+			}
+			fmt.Fprintf(out, "  %s: %s %s\n", field, value, faint("("+source+")"))
+		}
+		if disco.Provenance != nil {
+			for _, attempt := range disco.Provenance.Failed() {
+				fmt.Fprintf(out, "  failed %s: %s\n",
+					types.StrategySource(attempt.Runner, attempt.Strategy), attempt.Error)
+			}
+			if c := disco.Provenance.Collection; c != nil {
+				source := "kubernetes api"
+				if c.Context != "" {
+					source += " context " + c.Context
+				}
+				fmt.Fprintf(out, "  api: %s %s\n", c.Host, faint("("+source+")"))
+				if len(c.Forbidden) > 0 {
+					fmt.Fprintf(out, "  forbidden kinds: %s\n", strings.Join(c.Forbidden, ", "))
+				}
+				if len(c.Missing) > 0 {
+					fmt.Fprintf(out, "  missing kinds: %s\n", strings.Join(c.Missing, ", "))
+				}
+			}
+		}
+		fmt.Fprintln(out)
 	}
+}
 
-	// TODO: generalize the empty disco check
-	if resources.Len() == 0 && disco.IstioVersion == "" {
-		log.Fatal("failed to discovery any resources")
+// RunSnowcat runs the scanner.
+func RunSnowcat(cmd *cobra.Command, args []string) {
+	var err error
+
+	var failOn *types.Severity
+	if viper.GetString("fail-on") != "" {
+		severity := parseSeverityOption("fail-on")
+		failOn = &severity
+	}
+	minSeverity := parseSeverityOption("min-severity")
+	if err := checkFormat(formatFlag, formats); err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Fatal("invalid output format")
 	}
+	selected := selectAuditors()
 
-	if exportDirectoryFlag != "" {
+	inputs, err := parseInputs(args)
+	if err != nil {
 		log.WithFields(log.Fields{
-			"exportDirectory": exportDirectoryFlag,
-		}).Info("exporting resources")
+			"err": err,
+		}).Fatal("invalid input")
+	}
+	contexts := viper.GetStringSlice("context")
+	if len(inputs) > 0 && len(contexts) > 0 {
+		log.Fatal("contexts cannot be combined with input files")
+	}
 
-		err = resources.Export(exportDirectoryFlag)
+	clients := clientOptions()
+	interrupted, stop := interruptContext()
+	defer stop()
+	ctx, cancel := collectionContext(interrupted, viper.GetDuration("timeout"))
+	defer cancel()
+
+	var clusters []types.Cluster
+	var manifests []snapshot.Manifest
+	switch {
+	case len(inputs) > 0:
+		for _, in := range inputs {
+			cluster, manifest := loadInput(cmd, in)
+			clusters = append(clusters, cluster)
+			manifests = append(manifests, manifest)
+		}
+	case len(contexts) > 0:
+		for _, kubeContext := range contexts {
+			if ctx.Err() != nil {
+				break
+			}
+			// a single cluster is unnamed, as is a single input
+			name := kubeContext
+			if len(contexts) == 1 {
+				name = ""
+			}
+			cluster, manifest := loadContext(ctx, name, kubeContext)
+			clusters = append(clusters, cluster)
+			manifests = append(manifests, manifest)
+		}
+	default:
+		cluster, manifest := loadLive(ctx, clients)
+		clusters = append(clusters, cluster)
+		manifests = append(manifests, manifest)
+	}
+	if ctx.Err() != nil {
+		log.WithFields(log.Fields{
+			"err": ctx.Err(),
+		}).Warn("collection stopped early, results are partial")
+	}
+	if len(clusters) == 0 {
+		log.Fatal("collection stopped before any cluster was collected")
+	}
+
+	if path := viper.GetString("mesh-config"); path != "" {
+		if len(clusters) > 1 {
+			log.Fatal("a mesh config file cannot be given when scanning several clusters")
+		}
+		err = clusters[0].Resources.LoadMeshConfigFile(path)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"err": err,
-			}).Errorf("failed to export resources")
+			}).Fatal("failed to load mesh config")
 		}
 	}
+	// the istio ConfigMap is only taken from the istio namespace, which is
+	// known once discovery is done
+	for i := range clusters {
+		clusters[i].Resources.LoadMeshConfigMap(clusters[i].Discovery)
+	}
 
-	var results []types.AuditResult
-	for _, auditor := range auditors.All() {
-		log.WithFields(log.Fields{
-			"auditor": auditor.Name(),
-		}).Info("running auditor")
+	for i, cluster := range clusters {
+		// TODO: generalize the empty disco check
+		if cluster.Resources.Len() == 0 && cluster.Discovery.IstioVersion == "" {
+			// how discovery went is the first thing to look at
+			writeDiscovery(os.Stderr, []types.Cluster{cluster})
+			log.WithFields(log.Fields{
+				"cluster": cluster.Name,
+			}).Fatal("failed to discovery any resources")
+		}
 
-		res, err := auditor.Audit(disco, resources)
-		if err != nil {
+		if exportDirectoryFlag != "" {
+			dir := clusterPath(exportDirectoryFlag, cluster.Name, len(clusters))
 			log.WithFields(log.Fields{
-				"auditor": auditor.Name(),
-				"err":     err,
-			}).Error("auditor failed to run")
+				"exportDirectory": dir,
+			}).Info("exporting resources")
+
+			err = cluster.Resources.Export(dir)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"err": err,
+				}).Errorf("failed to export resources")
+			}
 		}
-		results = append(results, res...)
-	}
 
-	var out io.WriteCloser
-	if outputFileFlag != "" {
-		out, err = os.Create(outputFileFlag)
-		defer out.Close()
-	} else {
-		out = os.Stdout
+		if snapshotFileFlag != "" {
+			path := clusterPath(snapshotFileFlag, cluster.Name, len(clusters))
+			writeSnapshot(path, manifests[i], cluster.Discovery, cluster.Resources)
+		}
 	}
 
+	results, failed := runAuditors(auditContext(interrupted), selected, clusters)
+	results = suppressResults(results)
+	failure := scanFailure(results, failOn, failed)
+	results = filterBySeverity(results, minSeverity)
+
+	out := createOutput(outputFileFlag)
+	defer out.Close()
+
 	switch formatFlag {
 	case "json":
-		enc := json.NewEncoder(out)
-		enc.SetIndent("", "  ")
-		_ = enc.Encode(results)
-	case "text":
-		red := color.New(color.FgRed).SprintFunc()
-		yellow := color.New(color.FgYellow).SprintFunc()
-		for _, res := range results {
-			fmt.Fprintf(out, "%s [%s]: %s\n", red(res.Name), yellow(res.Resource), res.Description)
+		err = report.WriteJSON(out, results, clusters)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err": err,
+			}).Error("failed to write json results")
+		}
+	case "sarif":
+		err = report.WriteSARIF(out, selected, results, clusters)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err": err,
+			}).Error("failed to write sarif results")
+		}
+	case "html":
+		err = report.WriteHTML(out, selected, results, clusters)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err": err,
+			}).Error("failed to write html report")
+		}
+	case "junit":
+		err = report.WriteJUnit(out, selected, results, clusters)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err": err,
+			}).Error("failed to write junit results")
 		}
+	case "text":
+		writeDiscovery(out, clusters)
+		writeText(out, results)
 	}
 
-	if saveConfFlag {
-		saveFinalDiscovery(disco)
+	if saveConfFlag && len(clusters) > 1 {
+		log.Warn("not saving configuration, discoveries of several clusters cannot be saved")
+	} else if saveConfFlag {
+		saveFinalDiscovery(clusters[0].Discovery)
 
 		log.Info("saving configuration file based on new discoveries")
 
@@ -321,4 +777,10 @@ func RunSnowcat(args []string) {
 		fmt.Printf(jobCompleteMsg, namespace, podName, exportDirectoryFlag)
 		time.Sleep(5 * time.Minute)
 	}
+
+	if failure != nil {
+		log.WithFields(log.Fields{
+			"err": failure,
+		}).Fatal("scan failed")
+	}
 }
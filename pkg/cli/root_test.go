@@ -0,0 +1,125 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/viper"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func TestCheckFormat(t *testing.T) {
+	assert.Equal(t, nil, checkFormat("sarif", formats))
+	assert.Equal(t, nil, checkFormat("text", diffFormats))
+	assert.NotEqual(t, nil, checkFormat("sarif", diffFormats))
+	assert.NotEqual(t, nil, checkFormat("yaml", formats))
+	assert.NotEqual(t, nil, checkFormat("", formats))
+}
+
+func TestFilterResults(t *testing.T) {
+	results := []types.AuditResult{
+		{Auditor: "gateway-broad-hosts", Resource: "istio-system:public", Severity: types.High},
+		{Auditor: "gateway-broad-hosts", Resource: "istio-system:internal", Severity: types.Low},
+		{Auditor: "authz-allow-negative", Resource: "default:allow", Severity: types.Medium},
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "baseline.yaml")
+	data := `
+suppressions:
+- auditor: gateway-broad-hosts
+  resource: istio-system:public
+  justification: the public gateway serves every host
+- auditor: gateway-broad-hosts
+  resource: istio-system:internal
+  justification: the internal gateway is not exposed
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	type testcase struct {
+		description string
+		baseline    string
+		minSeverity string
+		resources   []string
+	}
+
+	testcases := []testcase{
+		{"no filters", "", "", []string{"istio-system:public", "istio-system:internal", "default:allow"}},
+		{"minimum severity", "", "medium", []string{"istio-system:public", "default:allow"}},
+		{"baseline", path, "", []string{"default:allow"}},
+		{"baseline and minimum severity", path, "high", nil},
+	}
+
+	hook := test.NewGlobal()
+	defer func() {
+		viper.Set("baseline", "")
+		viper.Set("min-severity", "")
+	}()
+	for _, tc := range testcases {
+		hook.Reset()
+		viper.Set("baseline", tc.baseline)
+		viper.Set("min-severity", tc.minSeverity)
+
+		var resources []string
+		for _, res := range filterResults(results) {
+			resources = append(resources, res.Resource)
+		}
+		assert.Equal(t, tc.resources, resources, tc.description)
+
+		// suppressions of results below the minimum severity still apply
+		for _, entry := range hook.AllEntries() {
+			assert.NotEqual(t, log.WarnLevel, entry.Level, tc.description, entry.Message)
+		}
+	}
+}
+
+func TestScanFailure(t *testing.T) {
+	high, critical := types.Severity(types.High), types.Severity(types.Critical)
+	results := []types.AuditResult{
+		{Resource: "istio-system:public", Severity: types.High},
+		{Resource: "default:allow", Severity: types.Low},
+	}
+
+	type testcase struct {
+		description string
+		results     []types.AuditResult
+		failOn      *types.Severity
+		failed      int
+		fails       bool
+	}
+
+	testcases := []testcase{
+		{"no fail-on", results, nil, 0, false},
+		{"results at the fail-on severity", results, &high, 0, true},
+		{"results below the fail-on severity", results, &critical, 0, false},
+		{"no results", nil, &high, 0, false},
+		{"failed auditor", nil, nil, 1, true},
+		{"failed auditor with fail-on", results, &critical, 2, true},
+	}
+
+	for _, tc := range testcases {
+		err := scanFailure(tc.results, tc.failOn, tc.failed)
+		assert.Equal(t, tc.fails, err != nil, tc.description)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/praetorian-inc/snowcat/pkg/runner"
+	"github.com/praetorian-inc/snowcat/pkg/snapshot"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// readSnapshot returns the bundle at path, or nil if path is not a bundle.
+func readSnapshot(path string) *snapshot.Snapshot {
+	if path == types.Stdin {
+		return nil
+	}
+	snap, err := snapshot.Read(path)
+	if errors.Is(err, snapshot.ErrNotBundle) {
+		return nil
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"input": path,
+			"err":   err,
+		}).Fatal("failed to read snapshot")
+	}
+
+	log.WithFields(log.Fields{
+		"input":     path,
+		"collected": snap.Manifest.CollectionFinished,
+		"resources": snap.Manifest.Resources,
+	}).Info("restored snapshot")
+	return snap
+}
+
+// snapshotDiscovery returns the discovery recorded in a snapshot, with the
+// facts given explicitly on the command line taking precedence.
+func snapshotDiscovery(cmd *cobra.Command, recorded, configured types.Discovery) types.Discovery {
+	disco := recorded
+	if recorded.Provenance != nil {
+		provenance := *recorded.Provenance
+		provenance.Fields = append([]types.FieldSource(nil), provenance.Fields...)
+		disco.Provenance = &provenance
+	} else {
+		// snapshots of older versions do not record provenance
+		runner.RecordSet(&disco, types.SourceSnapshot)
+	}
+
+	now := time.Now()
+	override := func(field runner.Field) {
+		disco.Provenance.SetField(string(field), types.SourceConfiguration, now)
+	}
+	flags := cmd.Flags()
+	if flags.Changed("istio-version") {
+		disco.IstioVersion = configured.IstioVersion
+		override(runner.IstioVersion)
+	}
+	if flags.Changed("istio-namespace") {
+		disco.IstioNamespace = configured.IstioNamespace
+		override(runner.IstioNamespace)
+	}
+	if flags.Changed("discovery-address") {
+		disco.DiscoveryAddress = configured.DiscoveryAddress
+		override(runner.DiscoveryAddress)
+	}
+	if flags.Changed("debugz-address") {
+		disco.DebugzAddress = configured.DebugzAddress
+		override(runner.DebugzAddress)
+	}
+	if flags.Changed("kubelet-addresses") {
+		disco.KubeletAddresses = configured.KubeletAddresses
+		override(runner.KubeletAddresses)
+	}
+	if flags.Changed("revisions") {
+		for _, rev := range configured.Revisions {
+			disco.AddRevision(rev)
+		}
+		override(runner.Revisions)
+	}
+	return disco
+}
+
+// writeSnapshot writes a bundle of the collected discovery and resources.
+func writeSnapshot(path string, manifest snapshot.Manifest, disco types.Discovery, resources types.Resources) {
+	manifest.Discovery = disco
+	if manifest.Source.Hostname == "" {
+		manifest.Source.Hostname, _ = os.Hostname()
+	}
+
+	log.WithFields(log.Fields{
+		"snapshot": path,
+	}).Info("writing snapshot")
+
+	err := snapshot.WriteFile(path, manifest, resources)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"err": err,
+		}).Error("failed to write snapshot")
+	}
+}
+
+// collectionManifest returns the manifest of a collection from inputPath, or
+// from the cluster if inputPath is empty, between start and finish.
+func collectionManifest(inputPath string, start, finish time.Time) snapshot.Manifest {
+	manifest := snapshot.Manifest{
+		CollectionStarted:  start.UTC(),
+		CollectionFinished: finish.UTC(),
+		Source:             snapshot.Source{Mode: snapshot.ModeCluster},
+	}
+	if inputPath != "" {
+		manifest.Source = snapshot.Source{Mode: snapshot.ModeFiles, Input: inputPath}
+	}
+	return manifest
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+	"github.com/spf13/cobra"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func TestSnapshotDiscovery(t *testing.T) {
+	recorded := types.Discovery{
+		IstioVersion:     "1.9.0",
+		IstioNamespace:   "istio-system",
+		DiscoveryAddress: "10.0.0.1:15010",
+		Revisions:        []types.Revision{{Name: types.DefaultRevision}},
+		Provenance: &types.Provenance{
+			Fields: []types.FieldSource{
+				{Field: "istioVersion", Source: "istiod/xds", Time: time.Unix(0, 0)},
+				{Field: "istioNamespace", Source: "istiod/xds", Time: time.Unix(0, 0)},
+			},
+		},
+	}
+	configured := types.Discovery{
+		IstioVersion:     "1.10.0",
+		IstioNamespace:   "istio-config",
+		DiscoveryAddress: "10.0.0.2:15010",
+		Revisions:        []types.Revision{{Name: "canary"}},
+	}
+
+	type testcase struct {
+		description string
+		flags       map[string]string
+		expected    types.Discovery
+		sources     map[string]string
+	}
+
+	testcases := []testcase{
+		{
+			"no flags",
+			nil,
+			types.Discovery{
+				IstioVersion:     "1.9.0",
+				IstioNamespace:   "istio-system",
+				DiscoveryAddress: "10.0.0.1:15010",
+				Revisions:        []types.Revision{{Name: types.DefaultRevision}},
+			},
+			map[string]string{"istioVersion": "istiod/xds", "istioNamespace": "istiod/xds"},
+		},
+		{
+			"version and namespace flags",
+			map[string]string{"istio-version": "1.10.0", "istio-namespace": "istio-config"},
+			types.Discovery{
+				IstioVersion:     "1.10.0",
+				IstioNamespace:   "istio-config",
+				DiscoveryAddress: "10.0.0.1:15010",
+				Revisions:        []types.Revision{{Name: types.DefaultRevision}},
+			},
+			map[string]string{"istioVersion": types.SourceConfiguration, "istioNamespace": types.SourceConfiguration},
+		},
+		{
+			"address and revisions flags",
+			map[string]string{"discovery-address": "10.0.0.2:15010", "revisions": "canary"},
+			types.Discovery{
+				IstioVersion:     "1.9.0",
+				IstioNamespace:   "istio-system",
+				DiscoveryAddress: "10.0.0.2:15010",
+				Revisions:        []types.Revision{{Name: types.DefaultRevision}, {Name: "canary"}},
+			},
+			map[string]string{
+				"istioVersion":     "istiod/xds",
+				"discoveryAddress": types.SourceConfiguration,
+				"revisions":        types.SourceConfiguration,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		cmd := &cobra.Command{}
+		for _, name := range []string{"istio-version", "istio-namespace", "discovery-address", "debugz-address"} {
+			cmd.Flags().String(name, "", "")
+		}
+		cmd.Flags().StringSlice("kubelet-addresses", nil, "")
+		cmd.Flags().StringSlice("revisions", nil, "")
+		for name, value := range tc.flags {
+			if err := cmd.Flags().Set(name, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		disco := snapshotDiscovery(cmd, recorded, configured)
+		for field, source := range tc.sources {
+			assert.Equal(t, source, disco.Provenance.Field(field).Source, tc.description, field)
+		}
+		disco.Provenance = nil
+		assert.Equal(t, tc.expected, disco, tc.description)
+	}
+
+	// the recorded provenance is left as it was
+	assert.Equal(t, 2, len(recorded.Provenance.Fields))
+	assert.Equal(t, "istiod/xds", recorded.Provenance.Fields[0].Source)
+}
+
+func TestSnapshotDiscoveryWithoutProvenance(t *testing.T) {
+	recorded := types.Discovery{IstioVersion: "1.9.0"}
+	disco := snapshotDiscovery(&cobra.Command{}, recorded, types.Discovery{})
+	assert.Equal(t, "1.9.0", disco.IstioVersion)
+	assert.Equal(t, types.SourceSnapshot, disco.Provenance.Field("istioVersion").Source)
+}
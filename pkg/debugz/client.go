@@ -24,43 +24,57 @@ import (
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	meshv1alpha1 "istio.io/api/mesh/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
 )
 
+// DefaultTimeout bounds each request when Options do not set a timeout.
+const DefaultTimeout = 5 * time.Second
+
+// Options configure a Client.
+type Options struct {
+	// Timeout bounds each request, including the request that verifies the
+	// address of a new client. It defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
 // Client wraps methods exposed by the istiod debug API.
 type Client struct {
 	debugAddr string
+	http      *http.Client
 
 	decoder runtime.Decoder
 }
 
 // NewClient creates a client for the istiod debug API.
-func NewClient(addr string) (*Client, error) {
+func NewClient(ctx context.Context, addr string, opts Options) (*Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
 	cli := &Client{
 		debugAddr: addr,
+		http:      &http.Client{Timeout: opts.Timeout},
 		decoder:   clientsetscheme.Codecs.UniversalDeserializer(),
 	}
-	return cli, cli.verify()
+	return cli, cli.verify(ctx)
 }
 
-func (c *Client) verify() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
-	defer cancel()
-
+func (c *Client) verify(ctx context.Context) error {
 	url := fmt.Sprintf("http://%s/debug/configz", c.debugAddr)
-	req, err := http.NewRequest("HEAD", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
 	if err != nil {
 		return err
 	}
-	req = req.WithContext(ctx)
 
 	log.WithFields(log.Fields{
 		"method": req.Method,
 		"url":    req.URL.String(),
 	}).Debug("validating debug API with HTTP request")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return err
 	}
@@ -76,18 +90,17 @@ func (c *Client) Resources(ctx context.Context) ([]runtime.Object, error) {
 	var configs []json.RawMessage
 
 	url := fmt.Sprintf("http://%s/debug/configz", c.debugAddr)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
 
 	log.WithFields(log.Fields{
 		"method": req.Method,
 		"url":    req.URL.String(),
 	}).Debug("sending HTTP request to debug API")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +123,34 @@ func (c *Client) Resources(ctx context.Context) ([]runtime.Object, error) {
 	return resources, nil
 }
 
+// MeshConfig queries the Istio debug server for the active mesh configuration.
+func (c *Client) MeshConfig(ctx context.Context) (*meshv1alpha1.MeshConfig, error) {
+	url := fmt.Sprintf("http://%s/debug/mesh", c.debugAddr)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"method": req.Method,
+		"url":    req.URL.String(),
+	}).Debug("sending HTTP request to debug API")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("status code %d from %s", resp.StatusCode, url)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return types.ParseMeshConfig(body)
+}
+
 func getVersionFromBody(body []byte) (string, error) {
 	r := regexp.MustCompile(`istio_version\": \"(.*)\",`)
 	matches := r.FindAllSubmatch(body, -1)
@@ -122,18 +163,17 @@ func getVersionFromBody(body []byte) (string, error) {
 // Version returns the Istio version from the debug API.
 func (c *Client) Version(ctx context.Context) (string, error) {
 	url := fmt.Sprintf("http://%s/debug/syncz", c.debugAddr)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", err
 	}
-	req = req.WithContext(ctx)
 
 	log.WithFields(log.Fields{
 		"method": req.Method,
 		"url":    req.URL.String(),
 	}).Debug("sending HTTP request to debug API")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -15,7 +15,12 @@
 package debugz
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bmizerany/assert"
 )
@@ -87,6 +92,28 @@ func TestGetMatchingVulns(t *testing.T) {
 	assert.Equal(t, "1.10.3", version)
 }
 
+func TestClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	_, err := NewClient(context.Background(), addr, Options{Timeout: 50 * time.Millisecond})
+	assert.NotEqual(t, nil, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = NewClient(ctx, addr, Options{Timeout: time.Minute})
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+	assert.NotEqual(t, nil, err)
+}
+
 // only usable if localhost 8080 is hosting this endpoint
 // func TestGetMatchingVulnsFromEndpoint(t *testing.T) {
 // 	url := "http://127.0.0.1:8080/debug/syncz"
@@ -0,0 +1,109 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff compares the results of two scans of the same target.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// Diff classifies the results of a new scan against an older scan.
+type Diff struct {
+	// New results are present only in the new scan.
+	New []types.AuditResult `json:"new"`
+	// Resolved results are present only in the old scan.
+	Resolved []types.AuditResult `json:"resolved"`
+	// Persisting results are present in both scans, as reported by the new scan.
+	Persisting []types.AuditResult `json:"persisting"`
+}
+
+// Compare matches results by fingerprint and classifies each as new, resolved
+// or persisting. Results without a fingerprint, such as those read from
+// reports of older versions, are fingerprinted before comparison.
+func Compare(older, newer []types.AuditResult) Diff {
+	diff := Diff{
+		New:        []types.AuditResult{},
+		Resolved:   []types.AuditResult{},
+		Persisting: []types.AuditResult{},
+	}
+
+	oldSeen := make(map[string]struct{})
+	for _, res := range older {
+		oldSeen[fingerprint(res)] = struct{}{}
+	}
+	newSeen := make(map[string]struct{})
+	for _, res := range newer {
+		fp := fingerprint(res)
+		newSeen[fp] = struct{}{}
+		if _, ok := oldSeen[fp]; ok {
+			diff.Persisting = append(diff.Persisting, res)
+		} else {
+			diff.New = append(diff.New, res)
+		}
+	}
+	for _, res := range older {
+		if _, ok := newSeen[fingerprint(res)]; !ok {
+			diff.Resolved = append(diff.Resolved, res)
+		}
+	}
+	return diff
+}
+
+func fingerprint(res types.AuditResult) string {
+	if res.Fingerprint != "" {
+		return res.Fingerprint
+	}
+	return types.Fingerprint(res)
+}
+
+// LoadResults reads results from a file written with --format json, either a
+// document with the results and discovery, or the list of results written by
+// older versions.
+func LoadResults(path string) ([]types.AuditResult, error) {
+	data, err := ioutil.ReadFile(path) // nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	return ParseResults(path, data)
+}
+
+// ParseResults parses results written with --format json, like LoadResults,
+// from data read from the named input.
+func ParseResults(name string, data []byte) ([]types.AuditResult, error) {
+	var results []types.AuditResult
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(data, &results); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	// other JSON documents, such as a kubectl List, decode without error
+	var doc struct {
+		Results *[]types.AuditResult `json:"results"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Results == nil {
+		return nil, fmt.Errorf("no results found in %s", name)
+	}
+	return *doc.Results, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	networkingapi "istio.io/api/networking/v1alpha3"
+	networking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/praetorian-inc/snowcat/auditors"
+	_ "github.com/praetorian-inc/snowcat/auditors/gateway"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func TestCompare(t *testing.T) {
+	gateway := types.AuditResult{
+		Auditor:     "gateway-broad-hosts",
+		Kind:        "Gateway",
+		Resource:    "default:broad",
+		Path:        "spec.servers[0].hosts[0]",
+		Description: "gateway is too broad",
+	}
+	reworded := gateway
+	reworded.Description = "wildcard host allowed"
+
+	peerauth := types.AuditResult{
+		Auditor:  "peerauth-permissive-mtls",
+		Kind:     "Namespace",
+		Resource: "default",
+	}
+	authz := types.AuditResult{
+		Auditor:  "authz-allow-negative",
+		Kind:     "AuthorizationPolicy",
+		Resource: "default:allow",
+		Path:     "spec.rules[0].to[0].operation.notPaths",
+	}
+
+	d := Compare([]types.AuditResult{gateway, peerauth}, []types.AuditResult{reworded, authz})
+
+	assert.Equal(t, []types.AuditResult{authz}, d.New)
+	assert.Equal(t, []types.AuditResult{peerauth}, d.Resolved)
+	assert.Equal(t, []types.AuditResult{reworded}, d.Persisting)
+}
+
+// auditGateway returns the results of the broad hosts auditor for a gateway
+// with the servers.
+func auditGateway(t *testing.T, servers ...*networkingapi.Server) []types.AuditResult {
+	selected, err := auditors.Select([]string{"gateway-broad-hosts"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources := types.NewResources()
+	resources.Gateways = []networking.Gateway{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ingress", Namespace: "istio-system"},
+			Spec:       networkingapi.Gateway{Servers: servers},
+		},
+	}
+
+	var results []types.AuditResult
+	for _, outcome := range auditors.Run(context.Background(), selected, types.Discovery{}, resources, auditors.RunOptions{}) {
+		assert.Equal(t, nil, outcome.Err)
+		for _, res := range outcome.Results {
+			res.Auditor = outcome.Auditor.ID()
+			res.Fingerprint = types.Fingerprint(res)
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+func TestCompareGainedServer(t *testing.T) {
+	wildcard := &networkingapi.Server{
+		Port:  &networkingapi.Port{Number: 80, Name: "http", Protocol: "HTTP"},
+		Hosts: []string{"*"},
+	}
+	gained := &networkingapi.Server{
+		Port:  &networkingapi.Port{Number: 443, Name: "https", Protocol: "HTTPS"},
+		Hosts: []string{"example.com"},
+	}
+
+	older := auditGateway(t, wildcard)
+	newer := auditGateway(t, gained, wildcard)
+	assert.Equal(t, "spec.servers[1].hosts[0]", newer[0].Path)
+
+	d := Compare(older, newer)
+	assert.Equal(t, []types.AuditResult{}, d.New)
+	assert.Equal(t, []types.AuditResult{}, d.Resolved)
+	assert.Equal(t, newer, d.Persisting)
+}
+
+func TestLoadResults(t *testing.T) {
+	type testcase struct {
+		description string
+		data        string
+	}
+	cases := []testcase{
+		{"list", `[{"auditor": "gateway-broad-hosts", "resource": "default:broad"}]`},
+		{"document", `{
+  "discovery": [{"istioNamespace": "istio-system"}],
+  "results": [{"auditor": "gateway-broad-hosts", "resource": "default:broad"}]
+}`},
+	}
+
+	dir := t.TempDir()
+	for _, tc := range cases {
+		path := filepath.Join(dir, tc.description+".json")
+		if err := os.WriteFile(path, []byte(tc.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		results, err := LoadResults(path)
+		assert.Equal(t, nil, err, tc.description)
+		assert.Equal(t, []types.AuditResult{{Auditor: "gateway-broad-hosts", Resource: "default:broad"}}, results, tc.description)
+	}
+
+	// manifests exported as JSON are not results
+	path := filepath.Join(dir, "kubectl.json")
+	data := `{"apiVersion": "v1", "kind": "List", "items": [{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "default"}}]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadResults(path)
+	assert.NotEqual(t, nil, err)
+}
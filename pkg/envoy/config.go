@@ -15,15 +15,27 @@
 package envoy
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spyzhov/ajson"
 )
 
+// DefaultTimeout bounds a request to the envoy admin API when Options do not
+// set a timeout.
+const DefaultTimeout = 5 * time.Second
+
+// Options configure requests to the envoy admin API.
+type Options struct {
+	// Timeout bounds each request. It defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
 // Config wraps the Envoy config_dump and exposes methods to extract data from it.
 type Config struct {
 	jpathNode *ajson.Node
@@ -63,13 +75,21 @@ func LoadConfig(configBytes []byte) (*Config, error) {
 }
 
 // RetrieveConfig fetches a Config from a local envoy service.
-func RetrieveConfig(envoyAdminURL string) (*Config, error) {
+func RetrieveConfig(ctx context.Context, envoyAdminURL string, opts Options) (*Config, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
 	log.WithFields(log.Fields{
 		"method": "GET",
 		"url":    envoyAdminURL,
 	}).Debug("sending HTTP request to envoy")
 
-	resp, err := http.Get(envoyAdminURL) // nolint:gosec
+	req, err := http.NewRequestWithContext(ctx, "GET", envoyAdminURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := (&http.Client{Timeout: opts.Timeout}).Do(req)
 	if err != nil {
 		return nil, err
 	}
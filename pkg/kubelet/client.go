@@ -26,34 +26,45 @@ import (
 	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
 )
 
+// DefaultTimeout bounds each request when Options do not set a timeout.
+const DefaultTimeout = 5 * time.Second
+
+// Options configure a Client.
+type Options struct {
+	// Timeout bounds each request, including the request that verifies the
+	// address of a new client. It defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
 // Client wraps methods exposed by the kubelet read-only API.
 type Client struct {
 	kubeletAddr string
+	http        *http.Client
 
 	decoder runtime.Decoder
 }
 
 // NewClient creates a kubelet client on the read-only port.
-func NewClient(addr string) (*Client, error) {
+func NewClient(ctx context.Context, addr string, opts Options) (*Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
 	cli := &Client{
 		kubeletAddr: addr,
+		http:        &http.Client{Timeout: opts.Timeout},
 		decoder:     clientsetscheme.Codecs.UniversalDeserializer(),
 	}
-	return cli, cli.verify()
+	return cli, cli.verify(ctx)
 }
 
-func (c *Client) verify() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
-	defer cancel()
-
+func (c *Client) verify(ctx context.Context) error {
 	url := fmt.Sprintf("http://%s/healthz/ping", c.kubeletAddr)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
-	req = req.WithContext(ctx)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return err
 	}
@@ -67,13 +78,12 @@ func (c *Client) verify() error {
 // Pods queries the read-only kubelet API for a list of pods running on that node.
 func (c *Client) Pods(ctx context.Context) ([]v1.Pod, error) {
 	url := fmt.Sprintf("http://%s/pods", c.kubeletAddr)
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req = req.WithContext(ctx)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,168 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	// embed is required for the report template
+	_ "embed"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+//go:embed report.html
+var htmlTemplate string
+
+// severities lists the severities from most to least severe.
+var severities = []types.Severity{
+	types.Critical, types.High, types.Medium, types.Low, types.None, types.Unknown,
+}
+
+type htmlReport struct {
+	Generated  string
+	Total      int
+	Severities []htmlSeverityCount
+	Auditors   []htmlAuditorSummary
+	Clusters   []htmlAuditorSummary
+	Namespaces []string
+	Findings   []htmlFinding
+}
+
+type htmlSeverityCount struct {
+	Severity string
+	Count    int
+}
+
+// htmlAuditorSummary counts the findings of an auditor, or of a cluster, by
+// severity.
+type htmlAuditorSummary struct {
+	ID     string
+	Name   string
+	Counts []int
+	Total  int
+}
+
+type htmlFinding struct {
+	types.AuditResult
+	SeverityName string
+	Namespace    string
+	YAML         string
+}
+
+// WriteHTML writes the results as a single self-contained HTML page, with a
+// summary by severity and auditor, a findings table that can be filtered by
+// namespace, and the YAML of each affected resource. When several clusters
+// were scanned, findings are also summarized by cluster.
+func WriteHTML(w io.Writer, auditors []types.Auditor, results []types.AuditResult, clusters []types.Cluster) error {
+	tmpl, err := template.New("report").Parse(htmlTemplate)
+	if err != nil {
+		return err
+	}
+
+	report := htmlReport{
+		Generated: time.Now().UTC().Format(time.RFC3339),
+		Total:     len(results),
+	}
+
+	severityIndex := make(map[types.Severity]int)
+	for i, severity := range severities {
+		severityIndex[severity] = i
+		report.Severities = append(report.Severities, htmlSeverityCount{Severity: severity.String()})
+	}
+
+	auditorIndex := make(map[string]int)
+	addAuditor := func(id, name string) {
+		auditorIndex[id] = len(report.Auditors)
+		report.Auditors = append(report.Auditors, htmlAuditorSummary{
+			ID:     id,
+			Name:   name,
+			Counts: make([]int, len(severities)),
+		})
+	}
+	for _, auditor := range auditors {
+		addAuditor(auditor.ID(), auditor.Name())
+	}
+
+	clusterIndex := make(map[string]int)
+	if len(clusters) > 1 {
+		for _, cluster := range clusters {
+			clusterIndex[cluster.Name] = len(report.Clusters)
+			report.Clusters = append(report.Clusters, htmlAuditorSummary{
+				ID:     cluster.Name,
+				Name:   cluster.Name,
+				Counts: make([]int, len(severities)),
+			})
+		}
+	}
+
+	namespaces := make(map[string]struct{})
+	for _, res := range results {
+		idx, ok := auditorIndex[res.Auditor]
+		if !ok {
+			addAuditor(res.Auditor, res.Name)
+			idx = auditorIndex[res.Auditor]
+		}
+		sev := severityIndex[res.Severity]
+		report.Severities[sev].Count++
+		report.Auditors[idx].Counts[sev]++
+		report.Auditors[idx].Total++
+		if idx, ok := clusterIndex[res.Cluster]; ok {
+			report.Clusters[idx].Counts[sev]++
+			report.Clusters[idx].Total++
+		}
+
+		finding := htmlFinding{
+			AuditResult:  res,
+			SeverityName: res.Severity.String(),
+			Namespace:    res.Namespace(),
+		}
+		if finding.Namespace != "" {
+			namespaces[finding.Namespace] = struct{}{}
+		}
+		cluster := types.FindCluster(clusters, res.Cluster)
+		if cluster == nil {
+			report.Findings = append(report.Findings, finding)
+			continue
+		}
+		if obj := cluster.Resources.Lookup(res.APIVersion, res.Kind, res.Resource); obj != nil {
+			data, err := types.EncodeYAML(obj)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"resource": res.Resource,
+					"err":      err,
+				}).Warn("failed to encode resource for report")
+			}
+			finding.YAML = string(data)
+		}
+		report.Findings = append(report.Findings, finding)
+	}
+
+	for ns := range namespaces {
+		report.Namespaces = append(report.Namespaces, ns)
+	}
+	sort.Strings(report.Namespaces)
+
+	// list the most severe findings first, keeping the order within a severity
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Severity > report.Findings[j].Severity
+	})
+
+	return tmpl.Execute(w, report)
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func TestWriteHTML(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "gateway.yaml"), []byte(gatewayYAML), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := types.NewResources()
+	if err := resources.LoadFromDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	auditors := []types.Auditor{
+		&fakeAuditor{id: "gateway-broad-hosts", name: "Overly Broad Gateway Hosts"},
+	}
+	results := []types.AuditResult{
+		{
+			Auditor:     "gateway-broad-hosts",
+			Name:        "Overly Broad Gateway Hosts",
+			Description: "<script>alert(1)</script>",
+			Severity:    types.High,
+			Resource:    "default:broad",
+			APIVersion:  "networking.istio.io/v1alpha3",
+			Kind:        "Gateway",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, auditors, results, []types.Cluster{{Resources: resources}}); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+
+	assert.Equal(t, true, strings.Contains(page, `<option value="default">default</option>`))
+	assert.Equal(t, true, strings.Contains(page, `<tr data-namespace="default">`))
+	assert.Equal(t, true, strings.Contains(page, "kind: Gateway"))
+	assert.Equal(t, false, strings.Contains(page, "<script>alert(1)</script>"))
+}
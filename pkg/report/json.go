@@ -0,0 +1,56 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// jsonReport is the document written with --format json.
+type jsonReport struct {
+	// Discovery holds the discovery of each cluster, with its provenance.
+	Discovery []jsonDiscovery     `json:"discovery"`
+	Results   []types.AuditResult `json:"results"`
+}
+
+type jsonDiscovery struct {
+	Cluster string `json:"cluster,omitempty"`
+	types.Discovery
+}
+
+// WriteJSON writes the results as a JSON document, along with the discovery
+// of each cluster and how its values were arrived at.
+func WriteJSON(w io.Writer, results []types.AuditResult, clusters []types.Cluster) error {
+	doc := jsonReport{
+		Discovery: []jsonDiscovery{},
+		Results:   results,
+	}
+	if doc.Results == nil {
+		doc.Results = []types.AuditResult{}
+	}
+	for _, cluster := range clusters {
+		doc.Discovery = append(doc.Discovery, jsonDiscovery{
+			Cluster:   cluster.Name,
+			Discovery: cluster.Discovery,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
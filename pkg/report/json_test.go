@@ -0,0 +1,53 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+func TestWriteJSON(t *testing.T) {
+	disco := types.Discovery{IstioNamespace: "istio-system"}
+	disco.Provenance = &types.Provenance{}
+	disco.Provenance.SetField("istioNamespace", "namespace/envoy", disco.Provenance.Started)
+	clusters := []types.Cluster{{Name: "east", Discovery: disco}}
+
+	var buf bytes.Buffer
+	err := WriteJSON(&buf, nil, clusters)
+	assert.Equal(t, nil, err)
+
+	var doc struct {
+		Discovery []struct {
+			Cluster        string           `json:"cluster"`
+			IstioNamespace string           `json:"istioNamespace"`
+			Provenance     types.Provenance `json:"provenance"`
+		} `json:"discovery"`
+		Results []types.AuditResult `json:"results"`
+	}
+	err = json.Unmarshal(buf.Bytes(), &doc)
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, 1, len(doc.Discovery))
+	assert.Equal(t, "east", doc.Discovery[0].Cluster)
+	assert.Equal(t, "istio-system", doc.Discovery[0].IstioNamespace)
+	assert.Equal(t, "namespace/envoy", doc.Discovery[0].Provenance.Field("istioNamespace").Source)
+	assert.Equal(t, []types.AuditResult{}, doc.Results)
+}
@@ -0,0 +1,178 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// globalCase names the test case of results that are not tied to a resource.
+const globalCase = "global"
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitFailureFor summarizes the results reported against a single resource.
+func junitFailureFor(results []types.AuditResult) *junitFailure {
+	if len(results) == 0 {
+		return nil
+	}
+
+	worst := results[0].Severity
+	var lines []string
+	for _, res := range results {
+		if res.Severity > worst {
+			worst = res.Severity
+		}
+		line := fmt.Sprintf("%s: %s", res.Severity, res.Description)
+		if res.Path != "" {
+			line = fmt.Sprintf("%s [%s]: %s", res.Severity, res.Path, res.Description)
+		}
+		if res.Remediation != "" {
+			line += "\nremediation: " + res.Remediation
+		}
+		lines = append(lines, line)
+	}
+
+	message := results[0].Description
+	if len(results) > 1 {
+		message = fmt.Sprintf("%d findings", len(results))
+	}
+	return &junitFailure{
+		Message: message,
+		Type:    worst.String(),
+		Text:    strings.Join(lines, "\n"),
+	}
+}
+
+// WriteJUnit writes the results as JUnit XML. Each auditor is a test suite
+// and each resource it evaluated is a test case, which fails if the auditor
+// reported results for it. Auditors that do not implement types.Scoper only
+// have test cases for the resources they reported, or a single passing test
+// case if they reported none. When several clusters were scanned, each
+// cluster has its own suites, named "cluster/auditor".
+func WriteJUnit(w io.Writer, auditors []types.Auditor, results []types.AuditResult, clusters []types.Cluster) error {
+	byCluster := make(map[string]map[string]map[string][]types.AuditResult)
+	for _, res := range results {
+		byAuditor := byCluster[res.Cluster]
+		if byAuditor == nil {
+			byAuditor = make(map[string]map[string][]types.AuditResult)
+			byCluster[res.Cluster] = byAuditor
+		}
+		if byAuditor[res.Auditor] == nil {
+			byAuditor[res.Auditor] = make(map[string][]types.AuditResult)
+		}
+		byAuditor[res.Auditor][res.Resource] = append(byAuditor[res.Auditor][res.Resource], res)
+	}
+
+	report := junitTestSuites{Name: toolName}
+	for _, cluster := range clusters {
+		for _, auditor := range auditors {
+			suite := junitSuite(auditor, byCluster[cluster.Name][auditor.ID()], cluster)
+			if len(clusters) > 1 {
+				suite.Name = cluster.Name + "/" + suite.Name
+			}
+			report.Tests += suite.Tests
+			report.Failures += suite.Failures
+			report.Suites = append(report.Suites, suite)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitSuite returns the test suite of an auditor run against a cluster,
+// given the results it reported by resource.
+func junitSuite(auditor types.Auditor, byResource map[string][]types.AuditResult, cluster types.Cluster) junitTestSuite {
+	scope := make(map[string]struct{})
+	if scoper, ok := auditor.(types.Scoper); ok {
+		for _, resource := range scoper.Scope(cluster.Discovery, cluster.Resources) {
+			scope[resource] = struct{}{}
+		}
+	}
+	for resource := range byResource {
+		scope[resource] = struct{}{}
+	}
+
+	names := make([]string, 0, len(scope))
+	for resource := range scope {
+		names = append(names, resource)
+	}
+	sort.Strings(names)
+
+	suite := junitTestSuite{Name: auditor.ID()}
+	for _, resource := range names {
+		name := resource
+		if name == "" {
+			name = globalCase
+		}
+		tc := junitTestCase{
+			Name:      name,
+			ClassName: auditor.ID(),
+			Failure:   junitFailureFor(byResource[resource]),
+		}
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	if len(suite.Cases) == 0 {
+		suite.Cases = append(suite.Cases, junitTestCase{
+			Name:      auditor.Name(),
+			ClassName: auditor.ID(),
+		})
+	}
+	suite.Tests = len(suite.Cases)
+	return suite
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+type fakeScoper struct {
+	fakeAuditor
+	scope []string
+}
+
+func (a *fakeScoper) Scope(types.Discovery, types.Resources) []string {
+	return a.scope
+}
+
+func TestWriteJUnit(t *testing.T) {
+	auditors := []types.Auditor{
+		&fakeScoper{
+			fakeAuditor: fakeAuditor{id: "gateway-broad-hosts", name: "Overly Broad Gateway Hosts"},
+			scope:       []string{"default:narrow", "default:broad"},
+		},
+		&fakeAuditor{id: "install-third-party-jwt", name: "Weak Service Account Authentication"},
+	}
+	results := []types.AuditResult{
+		{
+			Auditor:     "gateway-broad-hosts",
+			Description: "gateway is too broad",
+			Severity:    types.Low,
+			Resource:    "default:broad",
+			Path:        "spec.servers[0].hosts[0]",
+		},
+		{
+			Auditor:     "gateway-broad-hosts",
+			Description: "gateway is too broad",
+			Severity:    types.High,
+			Resource:    "default:broad",
+			Path:        "spec.servers[1].hosts[0]",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, auditors, results, []types.Cluster{{Resources: types.NewResources()}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 3, report.Tests)
+	assert.Equal(t, 1, report.Failures)
+	assert.Equal(t, 2, len(report.Suites))
+
+	gw := report.Suites[0]
+	assert.Equal(t, "gateway-broad-hosts", gw.Name)
+	assert.Equal(t, 2, gw.Tests)
+	assert.Equal(t, "default:broad", gw.Cases[0].Name)
+	assert.Equal(t, "2 findings", gw.Cases[0].Failure.Message)
+	assert.Equal(t, "high", gw.Cases[0].Failure.Type)
+	assert.Equal(t, "default:narrow", gw.Cases[1].Name)
+	assert.Equal(t, (*junitFailure)(nil), gw.Cases[1].Failure)
+
+	install := report.Suites[1]
+	assert.Equal(t, 1, install.Tests)
+	assert.Equal(t, 0, install.Failures)
+	assert.Equal(t, "Weak Service Account Authentication", install.Cases[0].Name)
+}
+
+func TestWriteJUnitClusters(t *testing.T) {
+	auditors := []types.Auditor{
+		&fakeAuditor{id: "gateway-broad-hosts", name: "Overly Broad Gateway Hosts"},
+	}
+	results := []types.AuditResult{
+		{
+			Auditor:     "gateway-broad-hosts",
+			Description: "gateway is too broad",
+			Severity:    types.High,
+			Resource:    "default:broad",
+			Cluster:     "west",
+		},
+	}
+	clusters := []types.Cluster{
+		{Name: "east", Resources: types.NewResources()},
+		{Name: "west", Resources: types.NewResources()},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, auditors, results, clusters); err != nil {
+		t.Fatal(err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 2, len(report.Suites))
+	assert.Equal(t, "east/gateway-broad-hosts", report.Suites[0].Name)
+	assert.Equal(t, 0, report.Suites[0].Failures)
+	assert.Equal(t, "west/gateway-broad-hosts", report.Suites[1].Name)
+	assert.Equal(t, 1, report.Suites[1].Failures)
+	assert.Equal(t, "default:broad", report.Suites[1].Cases[0].Name)
+}
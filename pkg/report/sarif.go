@@ -0,0 +1,197 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report renders audit results in formats consumed by other tools.
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	toolName = "snowcat"
+	toolURI  = "https://github.com/praetorian-inc/snowcat"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string           `json:"ruleId"`
+	RuleIndex  int              `json:"ruleIndex"`
+	Level      string           `json:"level"`
+	Message    sarifMessage     `json:"message"`
+	Locations  []sarifLocation  `json:"locations,omitempty"`
+	Properties *sarifProperties `json:"properties,omitempty"`
+}
+
+type sarifProperties struct {
+	Cluster  string `json:"cluster,omitempty"`
+	Revision string `json:"revision,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+// sarifLevel maps a finding's severity onto one of the SARIF result levels.
+// Unrated findings are reported as warnings, which is the SARIF default.
+func sarifLevel(severity types.Severity) string {
+	switch severity {
+	case types.Critical, types.High:
+		return "error"
+	case types.Medium, types.Unknown:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// WriteSARIF writes the results as a SARIF 2.1.0 log. Each auditor becomes a
+// rule of the snowcat tool and each result references the rule that produced
+// it. When resources were loaded from files, results point at the line of the
+// file where the affected resource starts. Results of one of several scanned
+// clusters, or of one of several control plane revisions, name their cluster
+// and revision in their properties.
+func WriteSARIF(w io.Writer, auditors []types.Auditor, results []types.AuditResult, clusters []types.Cluster) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:           toolName,
+				InformationURI: toolURI,
+				Rules:          []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+	}
+
+	ruleIndex := make(map[string]int)
+	for _, auditor := range auditors {
+		ruleIndex[auditor.ID()] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               auditor.ID(),
+			Name:             auditor.Name(),
+			ShortDescription: sarifMessage{Text: auditor.Name()},
+		})
+	}
+
+	for _, res := range results {
+		idx, ok := ruleIndex[res.Auditor]
+		if !ok {
+			// results from auditors outside of the provided list still need a rule
+			idx = len(run.Tool.Driver.Rules)
+			ruleIndex[res.Auditor] = idx
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+				ID:               res.Auditor,
+				Name:             res.Name,
+				ShortDescription: sarifMessage{Text: res.Name},
+			})
+		}
+
+		if rule := &run.Tool.Driver.Rules[idx]; rule.HelpURI == "" && len(res.References) > 0 {
+			rule.HelpURI = res.References[0]
+		}
+
+		result := sarifResult{
+			RuleID:    res.Auditor,
+			RuleIndex: idx,
+			Level:     sarifLevel(res.Severity),
+			Message:   sarifMessage{Text: res.Description},
+		}
+		if cluster := types.FindCluster(clusters, res.Cluster); cluster != nil {
+			for _, source := range cluster.Resources.Sources(res.APIVersion, res.Kind, res.Resource) {
+				result.Locations = append(result.Locations, sarifLocation{
+					PhysicalLocation: &sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: source.File},
+						Region:           &sarifRegion{StartLine: source.Line},
+					},
+				})
+			}
+		}
+		if len(result.Locations) == 0 && res.Resource != "" {
+			name := res.Resource
+			if res.Cluster != "" {
+				name = res.Cluster + "/" + name
+			}
+			result.Locations = append(result.Locations, sarifLocation{
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: name}},
+			})
+		}
+		if res.Cluster != "" || res.Revision != "" {
+			result.Properties = &sarifProperties{Cluster: res.Cluster, Revision: res.Revision}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,128 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const gatewayYAML = `apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: broad
+  namespace: default
+spec:
+  servers:
+  - hosts: ["*"]
+    port:
+      number: 80
+      name: http
+      protocol: HTTP
+`
+
+type fakeAuditor struct {
+	id   string
+	name string
+}
+
+func (a *fakeAuditor) ID() string {
+	return a.id
+}
+
+func (a *fakeAuditor) Name() string {
+	return a.name
+}
+
+func (a *fakeAuditor) Audit(types.Discovery, types.Resources) ([]types.AuditResult, error) {
+	return nil, nil
+}
+
+func TestWriteSARIF(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "gateway.yaml"), []byte(gatewayYAML), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := types.NewResources()
+	if err := resources.LoadFromDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	auditors := []types.Auditor{
+		&fakeAuditor{id: "gateway-broad-hosts", name: "Overly Broad Gateway Hosts"},
+		&fakeAuditor{id: "peerauth-permissive-mtls", name: "Permissive Mutual TLS"},
+	}
+	results := []types.AuditResult{
+		{
+			Auditor:     "gateway-broad-hosts",
+			Name:        "Overly Broad Gateway Hosts",
+			Description: "gateway is too broad",
+			Severity:    types.High,
+			Resource:    "default:broad",
+			APIVersion:  "networking.istio.io/v1alpha3",
+			Kind:        "Gateway",
+		},
+		{
+			Auditor:     "peerauth-permissive-mtls",
+			Name:        "Permissive Mutual TLS",
+			Description: "namespace missing PeerAuthentication policy",
+			Severity:    types.Medium,
+			Resource:    "default",
+			APIVersion:  "v1",
+			Kind:        "Namespace",
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, auditors, results, []types.Cluster{{Resources: resources}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, sarifVersion, log.Version)
+	assert.Equal(t, 1, len(log.Runs))
+
+	run := log.Runs[0]
+	assert.Equal(t, 2, len(run.Tool.Driver.Rules))
+	assert.Equal(t, 2, len(run.Results))
+
+	gw := run.Results[0]
+	assert.Equal(t, "gateway-broad-hosts", gw.RuleID)
+	assert.Equal(t, "error", gw.Level)
+	assert.Equal(t, 0, gw.RuleIndex)
+	assert.Equal(t, 1, len(gw.Locations))
+	assert.Equal(t, filepath.ToSlash(filepath.Join(dir, "gateway.yaml")),
+		gw.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 1, gw.Locations[0].PhysicalLocation.Region.StartLine)
+
+	ns := run.Results[1]
+	assert.Equal(t, "warning", ns.Level)
+	assert.Equal(t, 1, ns.RuleIndex)
+	assert.Equal(t, 1, len(ns.Locations))
+	assert.Equal(t, "default", ns.Locations[0].LogicalLocations[0].FullyQualifiedName)
+}
@@ -0,0 +1,318 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// Field names a fact of types.Discovery that a runner requires or provides.
+type Field string
+
+// The fields of types.Discovery that runners exchange.
+const (
+	IstioVersion     Field = "istioVersion"
+	IstioNamespace   Field = "istioNamespace"
+	DiscoveryAddress Field = "discoveryAddress"
+	DebugzAddress    Field = "debugzAddress"
+	KubeletAddresses Field = "kubeletAddresses"
+	Revisions        Field = "revisions"
+	RBAC             Field = "rbac"
+)
+
+// Fields lists every Field.
+var Fields = []Field{
+	IstioVersion,
+	IstioNamespace,
+	DiscoveryAddress,
+	DebugzAddress,
+	KubeletAddresses,
+	Revisions,
+	RBAC,
+}
+
+// RecordSet records source as what set every field that has a value in disco,
+// such as the fields given by configuration before discovery runs.
+func RecordSet(disco *types.Discovery, source string) {
+	if disco.Provenance == nil {
+		disco.Provenance = &types.Provenance{Started: time.Now()}
+	}
+	now := time.Now()
+	for _, f := range Fields {
+		if f.isSet(disco) {
+			disco.Provenance.SetField(string(f), source, now)
+		}
+	}
+	disco.Provenance.Finished = now
+}
+
+// value returns the value of the field in disco.
+func (f Field) value(disco *types.Discovery) interface{} {
+	switch f {
+	case IstioVersion:
+		return disco.IstioVersion
+	case IstioNamespace:
+		return disco.IstioNamespace
+	case DiscoveryAddress:
+		return disco.DiscoveryAddress
+	case DebugzAddress:
+		return disco.DebugzAddress
+	case KubeletAddresses:
+		return disco.KubeletAddresses
+	case Revisions:
+		return disco.Revisions
+	case RBAC:
+		return disco.RBAC
+	}
+	return nil
+}
+
+// Format returns the value of the field in disco for display, or an empty
+// string if it is not set.
+func (f Field) Format(disco *types.Discovery) string {
+	if !f.isSet(disco) {
+		return ""
+	}
+	switch v := f.value(disco).(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ", ")
+	case []types.Revision:
+		var names []string
+		for _, rev := range v {
+			names = append(names, rev.Name)
+		}
+		return strings.Join(names, ", ")
+	case *types.RBAC:
+		return v.ServiceAccount
+	}
+	return ""
+}
+
+// isSet returns true if the field has a value in disco.
+func (f Field) isSet(disco *types.Discovery) bool {
+	v := reflect.ValueOf(f.value(disco))
+	switch v.Kind() {
+	case reflect.Slice, reflect.String:
+		return v.Len() > 0
+	case reflect.Ptr:
+		return !v.IsNil()
+	}
+	return false
+}
+
+// copyTo copies the field from src to dst.
+func (f Field) copyTo(dst, src *types.Discovery) {
+	switch f {
+	case IstioVersion:
+		dst.IstioVersion = src.IstioVersion
+	case IstioNamespace:
+		dst.IstioNamespace = src.IstioNamespace
+	case DiscoveryAddress:
+		dst.DiscoveryAddress = src.DiscoveryAddress
+	case DebugzAddress:
+		dst.DebugzAddress = src.DebugzAddress
+	case KubeletAddresses:
+		dst.KubeletAddresses = src.KubeletAddresses
+	case Revisions:
+		dst.Revisions = src.Revisions
+	case RBAC:
+		dst.RBAC = src.RBAC
+	}
+}
+
+// cloneDiscovery returns a copy of disco that shares no slices with it, so a
+// runner can modify it while others read the original. The provenance is
+// left out, it is only recorded in the original.
+func cloneDiscovery(disco *types.Discovery) *types.Discovery {
+	clone := *disco
+	clone.Provenance = nil
+	clone.KubeletAddresses = append([]string(nil), disco.KubeletAddresses...)
+	clone.Revisions = append([]types.Revision(nil), disco.Revisions...)
+	return &clone
+}
+
+// runState tracks a runner while the runners are scheduled.
+type runState struct {
+	runner  *Runner
+	pending bool
+	running bool
+	runs    int
+	// missing are the required fields, provided by other runners, that were
+	// not set when the runner last started.
+	missing []Field
+}
+
+// completion is a runner that finished with the discovery it produced.
+type completion struct {
+	index int
+	start *types.Discovery
+	out   *types.Discovery
+	trace trace
+}
+
+// discover runs the runners concurrently, each one once the runners that
+// provide the fields it requires have finished. A runner whose required
+// fields are filled in only after it started is run again. Runners that
+// depend on each other are started in the order of the list. Once ctx is
+// done no runner is started, and discover returns when the running ones
+// finish.
+func (runners Runners) discover(ctx context.Context, clients Clients, disco *types.Discovery) {
+	if disco.Provenance == nil {
+		disco.Provenance = &types.Provenance{Started: time.Now()}
+	}
+
+	states := make([]*runState, len(runners))
+	for i := range runners {
+		states[i] = &runState{runner: &runners[i], pending: true}
+	}
+
+	// blocked returns true if a runner that provides a field required by
+	// state has yet to finish.
+	blocked := func(state *runState) bool {
+		for _, other := range states {
+			if other == state || (!other.pending && !other.running) {
+				continue
+			}
+			if provides(other.runner, state.runner.Requires) {
+				return true
+			}
+		}
+		return false
+	}
+
+	done := make(chan completion)
+	start := func(i int) {
+		state := states[i]
+		state.pending = false
+		state.running = true
+		state.runs++
+		state.missing = nil
+		for _, f := range state.runner.Requires {
+			if !f.isSet(disco) && !provides(state.runner, []Field{f}) {
+				state.missing = append(state.missing, f)
+			}
+		}
+
+		in, out := cloneDiscovery(disco), cloneDiscovery(disco)
+		go func() {
+			t, err := state.runner.run(ctx, clients, out)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"runner": state.runner.Name,
+					"err":    err,
+				}).Warn("failed to run")
+			}
+			done <- completion{index: i, start: in, out: out, trace: t}
+		}()
+	}
+
+	running := 0
+	for {
+		if ctx.Err() != nil {
+			if running == 0 {
+				return
+			}
+			c := <-done
+			running--
+			states[c.index].running = false
+			merge(disco, states[c.index].runner, c)
+			continue
+		}
+
+		started := false
+		for i, state := range states {
+			if state.pending && !blocked(state) {
+				start(i)
+				running++
+				started = true
+			}
+		}
+		if running == 0 && !started {
+			// the remaining runners depend on each other, start the first
+			next := -1
+			for i, state := range states {
+				if state.pending {
+					next = i
+					break
+				}
+			}
+			if next < 0 {
+				return
+			}
+			start(next)
+			running++
+		}
+
+		c := <-done
+		running--
+		state := states[c.index]
+		state.running = false
+
+		merge(disco, state.runner, c)
+
+		for _, other := range states {
+			if other.pending || other.running {
+				continue
+			}
+			for _, f := range other.missing {
+				if f.isSet(disco) {
+					log.WithFields(log.Fields{
+						"runner": other.runner.Name,
+						"field":  f,
+					}).Info("rerunning discovery with newly discovered field")
+					other.pending = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// merge copies the fields that a finished runner provides into disco, along
+// with the provenance the runner recorded. only the provided fields that the
+// runner changed are kept, so concurrent runners do not undo each other's
+// discoveries.
+func merge(disco *types.Discovery, r *Runner, c completion) {
+	disco.Provenance.Attempts = append(disco.Provenance.Attempts, c.trace.attempts...)
+	for _, f := range r.Provides {
+		if !reflect.DeepEqual(f.value(c.start), f.value(c.out)) {
+			f.copyTo(disco, c.out)
+			if src, ok := c.trace.sources[f]; ok {
+				disco.Provenance.SetField(string(f), src.Source, src.Time)
+			}
+		}
+	}
+}
+
+// provides returns true if the runner provides any of the fields.
+func provides(r *Runner, fields []Field) bool {
+	for _, p := range r.Provides {
+		for _, f := range fields {
+			if p == f {
+				return true
+			}
+		}
+	}
+	return false
+}
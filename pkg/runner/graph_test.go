@@ -0,0 +1,161 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// funcStrategy runs run as its strategy.
+type funcStrategy struct {
+	run func(input *types.Discovery) error
+}
+
+func (s *funcStrategy) Name() string {
+	return "func"
+}
+
+func (s *funcStrategy) Run(ctx context.Context, clients Clients, input *types.Discovery) error {
+	return s.run(input)
+}
+
+// recorder records the order in which runners ran.
+type recorder struct {
+	mu   sync.Mutex
+	runs []string
+}
+
+func (r *recorder) runner(name string, requires, provides []Field, run func(input *types.Discovery) error) Runner {
+	return Runner{
+		Name: name,
+		Strategies: []Strategy{&funcStrategy{run: func(input *types.Discovery) error {
+			r.mu.Lock()
+			r.runs = append(r.runs, name)
+			r.mu.Unlock()
+			return run(input)
+		}}},
+		Requires: requires,
+		Provides: provides,
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	rec := &recorder{}
+	var seen string
+	runners := Runners{
+		rec.runner("istiod", []Field{IstioNamespace}, []Field{DiscoveryAddress}, func(input *types.Discovery) error {
+			seen = input.IstioNamespace
+			input.DiscoveryAddress = "istiod." + input.IstioNamespace + ":15010"
+			// fields that are not provided are discarded
+			input.IstioVersion = "1.0.0"
+			return nil
+		}),
+		rec.runner("namespace", nil, []Field{IstioNamespace}, func(input *types.Discovery) error {
+			input.IstioNamespace = "istio-system"
+			return nil
+		}),
+	}
+
+	var disco types.Discovery
+	runners.discover(context.Background(), Clients{}, &disco)
+
+	assert.Equal(t, []string{"namespace", "istiod"}, rec.runs)
+	assert.Equal(t, "istio-system", seen)
+	assert.Equal(t, "istiod.istio-system:15010", disco.DiscoveryAddress)
+	assert.Equal(t, "", disco.IstioVersion)
+}
+
+func TestDiscoverRerun(t *testing.T) {
+	rec := &recorder{}
+	runners := Runners{
+		// depends on the runner below, which depends on it in turn
+		rec.runner("kubelet", []Field{IstioNamespace}, []Field{KubeletAddresses}, func(input *types.Discovery) error {
+			if input.IstioNamespace == "" {
+				return fmt.Errorf("istio namespace required")
+			}
+			input.KubeletAddresses = []string{"10.0.0.1:10255"}
+			return nil
+		}),
+		rec.runner("namespace", []Field{KubeletAddresses}, []Field{IstioNamespace}, func(input *types.Discovery) error {
+			input.IstioNamespace = "istio-system"
+			return nil
+		}),
+	}
+
+	var disco types.Discovery
+	runners.discover(context.Background(), Clients{}, &disco)
+
+	assert.Equal(t, []string{"kubelet", "namespace", "kubelet", "namespace"}, rec.runs)
+	assert.Equal(t, []string{"10.0.0.1:10255"}, disco.KubeletAddresses)
+}
+
+func TestDiscoverCanceled(t *testing.T) {
+	rec := &recorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	runners := Runners{
+		rec.runner("istiod", []Field{IstioNamespace}, []Field{DiscoveryAddress}, func(input *types.Discovery) error {
+			input.DiscoveryAddress = "istiod." + input.IstioNamespace + ":15010"
+			return nil
+		}),
+		rec.runner("namespace", nil, []Field{IstioNamespace}, func(input *types.Discovery) error {
+			input.IstioNamespace = "istio-system"
+			cancel()
+			return nil
+		}),
+	}
+
+	var disco types.Discovery
+	runners.discover(ctx, Clients{}, &disco)
+
+	// the running runner's discoveries are kept, later runners are skipped
+	assert.Equal(t, []string{"namespace"}, rec.runs)
+	assert.Equal(t, "istio-system", disco.IstioNamespace)
+	assert.Equal(t, "", disco.DiscoveryAddress)
+}
+
+func TestDiscoverProvenance(t *testing.T) {
+	failing := &funcStrategy{run: func(input *types.Discovery) error {
+		return fmt.Errorf("no envoy admin api")
+	}}
+	fallback := &funcStrategy{run: func(input *types.Discovery) error {
+		input.IstioNamespace = "istio-system"
+		return nil
+	}}
+	runners := Runners{{
+		Name:       "namespace",
+		Strategies: []Strategy{failing, fallback},
+		Provides:   []Field{IstioNamespace},
+	}}
+
+	disco := types.Discovery{KubeletAddresses: []string{"10.0.0.1:10255"}}
+	RecordSet(&disco, types.SourceConfiguration)
+	runners.discover(context.Background(), Clients{}, &disco)
+
+	assert.Equal(t, "namespace/func", disco.Provenance.Field(string(IstioNamespace)).Source)
+	assert.Equal(t, types.SourceConfiguration, disco.Provenance.Field(string(KubeletAddresses)).Source)
+	assert.T(t, disco.Provenance.Field(string(DiscoveryAddress)) == nil)
+
+	assert.Equal(t, 2, len(disco.Provenance.Attempts))
+	failed := disco.Provenance.Failed()
+	assert.Equal(t, 1, len(failed))
+	assert.Equal(t, "no envoy admin api", failed[0].Error)
+}
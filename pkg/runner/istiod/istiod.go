@@ -20,11 +20,14 @@
 //    if provided with an ip:port combination known to be running the kubelet api,
 //    this strategy can query for running pods, check them for istio related
 //    labels, and determine whether or not they are running the debug/discovery
-//    services
+//    services. every control plane revision found is recorded
 //
 // IstiodStrategy:
 //    if provided with the istio namespace, it will attempt to locate the
-//    debug/discovery service at `istiod.{namespace}.svc.cluster.local`
+//    debug/discovery service at `istiod.{namespace}.svc.cluster.local`, and
+//    that of each known revision at `istiod-{revision}.{namespace}.svc.cluster.local`.
+//    revisions are known from the configuration, or from the istiod
+//    deployments and services collected from the kubernetes api
 //
 // IstioPilotStrategy:
 //    if provided with the istio namespace, it will attempt to locate the
@@ -39,6 +42,7 @@ package istiod
 import (
 	"context"
 	"fmt"
+	"regexp"
 
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
@@ -61,8 +65,13 @@ var Runner = runner.Runner{
 		&istioPilotStrategy{},
 		&envoyConfigStrategy{},
 	},
+	Requires: []runner.Field{runner.KubeletAddresses, runner.IstioNamespace},
+	Provides: []runner.Field{runner.DiscoveryAddress, runner.DebugzAddress, runner.Revisions},
 }
 
+// revisionHost matches the service host of the istiod of a revision.
+var revisionHost = regexp.MustCompile(`^istiod-([^.:]+)\.`)
+
 func isRunning(pod v1.Pod) bool {
 	return pod.Status.Phase == v1.PodRunning
 }
@@ -73,8 +82,12 @@ func isIstiod(pod v1.Pod) bool {
 		pod.Labels["operator.istio.io/component"] == "Pilot"
 }
 
-func hasDiscoveryService(host string) bool {
-	c, err := xds.NewClient(host + ":15010")
+// hasDiscoveryService reports whether istiod's xds is served at host. The
+// secure xds of a pod IP is verified against serverName.
+func hasDiscoveryService(ctx context.Context, clients runner.Clients, host, serverName string) bool {
+	opts := clients.XDS
+	opts.ServerName = serverName
+	c, err := xds.NewClient(ctx, host+":"+opts.Port(), opts)
 	if err != nil {
 		return false
 	}
@@ -82,24 +95,11 @@ func hasDiscoveryService(host string) bool {
 	return true
 }
 
-func hasDebugService(host string) bool {
-	_, err := debugz.NewClient(host + ":8080")
+func hasDebugService(ctx context.Context, clients runner.Clients, host string) bool {
+	_, err := debugz.NewClient(ctx, host+":8080", clients.Debugz)
 	return err == nil
 }
 
-func isDebugIstiod(host string) bool {
-	if host == "" {
-		return false
-	}
-	if hasDiscoveryService(host) {
-		return true
-	}
-	if hasDebugService(host) {
-		return true
-	}
-	return false
-}
-
 type kubeletStrategy struct{}
 
 // Name returns the strategy name for reporting purposes.
@@ -109,13 +109,11 @@ func (s *kubeletStrategy) Name() string {
 
 // Run executes the kubelet strategy and populates the Discovery type's
 // DiscoveryAddress and DebugzAddress if it can verify the results.
-func (s *kubeletStrategy) Run(input *types.Discovery) error {
-	ctx := context.Background()
-
-	var ips []string
+func (s *kubeletStrategy) Run(ctx context.Context, clients runner.Clients, input *types.Discovery) error {
+	var revisions []types.Revision
 
 	for _, addr := range input.KubeletAddresses {
-		k, err := kubelet.NewClient(addr)
+		k, err := kubelet.NewClient(ctx, addr, clients.Kubelet)
 		if err != nil {
 			log.WithFields(log.Fields{
 				"addr": addr,
@@ -135,30 +133,31 @@ func (s *kubeletStrategy) Run(input *types.Discovery) error {
 
 		for _, pod := range pods {
 			ip := pod.Status.PodIP
-			if isRunning(pod) && isIstiod(pod) && isDebugIstiod(ip) {
-				ips = append(ips, ip)
+			if !isRunning(pod) || !isIstiod(pod) || ip == "" {
+				continue
+			}
+			rev := types.Revision{Name: types.RevisionOf(pod.Labels)}
+			if hasDiscoveryService(ctx, clients, ip, xds.ServiceName(rev.Name, pod.Namespace)) {
+				rev.DiscoveryAddress = ip + ":" + clients.XDS.Port()
+			}
+			if hasDebugService(ctx, clients, ip) {
+				rev.DebugzAddress = ip + ":8080"
+			}
+			if rev.DiscoveryAddress != "" || rev.DebugzAddress != "" {
+				revisions = append(revisions, rev)
 			}
 		}
 	}
 
-	if len(ips) == 0 {
+	if len(revisions) == 0 {
 		return fmt.Errorf("failed to find istiod")
 	}
 
-	var found bool
-	for _, ip := range ips {
-		if hasDiscoveryService(ip) {
-			input.DiscoveryAddress = ip + ":15010"
-			found = true
-		}
-		if hasDebugService(ip) {
-			input.DebugzAddress = ip + ":8080"
-			found = true
-		}
-		if found {
-			break
-		}
+	// the first verified pod of each revision is used
+	for _, rev := range revisions {
+		input.AddRevision(rev)
 	}
+	input.SetPrimaryRevision()
 	return nil
 }
 
@@ -170,18 +169,35 @@ func (s *istiodStrategy) Name() string {
 }
 
 // Run executes the istiod strategy and populates the Discovery type's
-// DiscoveryAddress and DebugzAddress if it can verify the results.
-func (s *istiodStrategy) Run(input *types.Discovery) error {
+// DiscoveryAddress and DebugzAddress if it can verify the results. The
+// services of the default revision and of each revision already named in the
+// Discovery, by the configuration or the resources collected from the
+// kubernetes api, are tried.
+func (s *istiodStrategy) Run(ctx context.Context, clients runner.Clients, input *types.Discovery) error {
 	if input.IstioNamespace == "" {
 		return fmt.Errorf("istio namespace required")
 	}
-	addr := fmt.Sprintf("istiod.%s.svc.cluster.local", input.IstioNamespace)
-	if hasDiscoveryService(addr) {
-		input.DiscoveryAddress = addr + ":15010"
+
+	names := []string{types.DefaultRevision}
+	for _, rev := range input.Revisions {
+		if rev.Name != types.DefaultRevision {
+			names = append(names, rev.Name)
+		}
 	}
-	if hasDebugService(addr) {
-		input.DebugzAddress = addr + ":8080"
+	for _, name := range names {
+		addr := xds.ServiceName(name, input.IstioNamespace) + ".cluster.local"
+		rev := types.Revision{Name: name}
+		if hasDiscoveryService(ctx, clients, addr, "") {
+			rev.DiscoveryAddress = addr + ":" + clients.XDS.Port()
+		}
+		if hasDebugService(ctx, clients, addr) {
+			rev.DebugzAddress = addr + ":8080"
+		}
+		if rev.DiscoveryAddress != "" || rev.DebugzAddress != "" {
+			input.AddRevision(rev)
+		}
 	}
+	input.SetPrimaryRevision()
 	return nil
 }
 
@@ -194,15 +210,15 @@ func (s *istioPilotStrategy) Name() string {
 
 // Run executes the istio-pilot strategy and populates the Discovery type's
 // DiscoveryAddress and DebugzAddress if it can verify the results.
-func (s *istioPilotStrategy) Run(input *types.Discovery) error {
+func (s *istioPilotStrategy) Run(ctx context.Context, clients runner.Clients, input *types.Discovery) error {
 	if input.IstioNamespace == "" {
 		return fmt.Errorf("istio namespace required")
 	}
 	addr := fmt.Sprintf("istio-pilot.%s.svc.cluster.local", input.IstioNamespace)
-	if hasDiscoveryService(addr) {
-		input.DiscoveryAddress = addr + ":15010"
+	if hasDiscoveryService(ctx, clients, addr, "") {
+		input.DiscoveryAddress = addr + ":" + clients.XDS.Port()
 	}
-	if hasDebugService(addr) {
+	if hasDebugService(ctx, clients, addr) {
 		input.DebugzAddress = addr + ":8080"
 	}
 	return nil
@@ -217,8 +233,8 @@ func (s *envoyConfigStrategy) Name() string {
 
 // Run executes the envoy config strategy and populates the Discovery type's
 // DiscoveryAddress if it can verify the results.
-func (s *envoyConfigStrategy) Run(input *types.Discovery) error {
-	ec, err := envoy.RetrieveConfig("http://localhost:15000/config_dump")
+func (s *envoyConfigStrategy) Run(ctx context.Context, clients runner.Clients, input *types.Discovery) error {
+	ec, err := envoy.RetrieveConfig(ctx, "http://localhost:15000/config_dump", clients.Envoy)
 	if err != nil {
 		return err
 	}
@@ -227,12 +243,17 @@ func (s *envoyConfigStrategy) Run(input *types.Discovery) error {
 		return err
 	}
 
-	c, err := xds.NewClient(addr)
+	c, err := xds.NewClient(ctx, addr, clients.XDS)
 	if err != nil {
 		return err
 	}
 	c.Close()
 
-	input.DiscoveryAddress = addr
+	rev := types.Revision{Name: types.DefaultRevision, DiscoveryAddress: addr}
+	if match := revisionHost.FindStringSubmatch(addr); match != nil {
+		rev.Name = match[1]
+	}
+	input.AddRevision(rev)
+	input.SetPrimaryRevision()
 	return nil
 }
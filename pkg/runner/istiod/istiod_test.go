@@ -56,3 +56,25 @@ func TestIsIstiod(t *testing.T) {
 		}
 	}
 }
+
+func TestRevisionHost(t *testing.T) {
+	type testcase struct {
+		addr     string
+		expected string
+	}
+
+	testcases := []testcase{
+		{addr: "istiod.istio-system.svc:15012", expected: ""},
+		{addr: "istiod-1-14.istio-system.svc:15012", expected: "1-14"},
+		{addr: "istiod-canary.istio-system.svc.cluster.local:15010", expected: "canary"},
+	}
+	for i, tc := range testcases {
+		var rev string
+		if match := revisionHost.FindStringSubmatch(tc.addr); match != nil {
+			rev = match[1]
+		}
+		if rev != tc.expected {
+			t.Errorf("[%d] got %q, expected %q", i, rev, tc.expected)
+		}
+	}
+}
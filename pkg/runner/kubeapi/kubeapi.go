@@ -0,0 +1,309 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kubeapi implements a runner to collect resources from the
+// kubernetes api server. unlike the other runners, which rely on
+// unauthenticated side channels, it authenticates with a kubeconfig or the
+// in-cluster service account, and lists every kind that types.Resources
+// supports across all namespaces.
+//
+// kinds that the credentials may not list are reported as forbidden, and kinds
+// that the api server does not serve, such as those of an uninstalled CRD, are
+// reported as missing. neither stops the collection of the remaining kinds.
+package kubeapi
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hashicorp/go-multierror"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/pager"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const (
+	// DefaultPageSize is the number of objects requested per page of a list.
+	DefaultPageSize = 500
+
+	// qps and burst raise the client rate limits, since a collection sends
+	// at least one request per kind in quick succession.
+	qps   = 50
+	burst = 100
+)
+
+// kind is a resource collected from the api server, along with the versions
+// to request it in, in order of preference.
+type kind struct {
+	group    string
+	resource string
+	versions []string
+	// metadataOnly lists only the metadata of the objects, so that the
+	// content of secrets never leaves the api server.
+	metadataOnly bool
+}
+
+func (k kind) String() string {
+	if k.group == "" {
+		return k.resource
+	}
+	return k.resource + "." + k.group
+}
+
+// kinds are the resources of every kind that types.Resources holds.
+var kinds = []kind{
+	{group: "", resource: "namespaces", versions: []string{"v1"}},
+	{group: "", resource: "pods", versions: []string{"v1"}},
+	{group: "", resource: "services", versions: []string{"v1"}},
+	{group: "", resource: "endpoints", versions: []string{"v1"}},
+	{group: "", resource: "serviceaccounts", versions: []string{"v1"}},
+	{group: "", resource: "configmaps", versions: []string{"v1"}},
+	{group: "", resource: "secrets", versions: []string{"v1"}, metadataOnly: true},
+	{group: "discovery.k8s.io", resource: "endpointslices", versions: []string{"v1"}},
+	{group: "apps", resource: "deployments", versions: []string{"v1"}},
+	{group: "apps", resource: "replicasets", versions: []string{"v1"}},
+	{group: "apps", resource: "statefulsets", versions: []string{"v1"}},
+	{group: "apps", resource: "daemonsets", versions: []string{"v1"}},
+	{group: "networking.k8s.io", resource: "networkpolicies", versions: []string{"v1"}},
+	{group: "security.istio.io", resource: "peerauthentications", versions: []string{"v1", "v1beta1"}},
+	{group: "security.istio.io", resource: "authorizationpolicies", versions: []string{"v1", "v1beta1"}},
+	{group: "security.istio.io", resource: "requestauthentications", versions: []string{"v1", "v1beta1"}},
+	{group: "networking.istio.io", resource: "destinationrules", versions: []string{"v1", "v1beta1", "v1alpha3"}},
+	{group: "networking.istio.io", resource: "gateways", versions: []string{"v1", "v1beta1", "v1alpha3"}},
+	{group: "networking.istio.io", resource: "virtualservices", versions: []string{"v1", "v1beta1", "v1alpha3"}},
+	{group: "networking.istio.io", resource: "envoyfilters", versions: []string{"v1alpha3"}},
+	{group: "networking.istio.io", resource: "serviceentries", versions: []string{"v1", "v1beta1", "v1alpha3"}},
+	{group: "networking.istio.io", resource: "sidecars", versions: []string{"v1", "v1beta1", "v1alpha3"}},
+	{group: "networking.istio.io", resource: "workloadentries", versions: []string{"v1", "v1beta1", "v1alpha3"}},
+	{group: "networking.istio.io", resource: "workloadgroups", versions: []string{"v1", "v1beta1", "v1alpha3"}},
+	{group: "networking.istio.io", resource: "proxyconfigs", versions: []string{"v1beta1"}},
+	{group: "telemetry.istio.io", resource: "telemetries", versions: []string{"v1", "v1alpha1"}},
+	{group: "extensions.istio.io", resource: "wasmplugins", versions: []string{"v1alpha1"}},
+	{group: "gateway.networking.k8s.io", resource: "gatewayclasses", versions: []string{"v1beta1", "v1alpha2"}},
+	{group: "gateway.networking.k8s.io", resource: "gateways", versions: []string{"v1beta1", "v1alpha2"}},
+	{group: "gateway.networking.k8s.io", resource: "httproutes", versions: []string{"v1beta1", "v1alpha2"}},
+	{group: "gateway.networking.k8s.io", resource: "referencegrants", versions: []string{"v1beta1", "v1alpha2"}},
+	{group: "gateway.networking.k8s.io", resource: "tcproutes", versions: []string{"v1alpha2"}},
+	{group: "gateway.networking.k8s.io", resource: "tlsroutes", versions: []string{"v1alpha2"}},
+	{group: "gateway.networking.k8s.io", resource: "grpcroutes", versions: []string{"v1alpha2"}},
+}
+
+// Config returns the configuration of a client for the api server. it is
+// loaded like kubectl does, from the kubeconfig file and context if given, or
+// from $KUBECONFIG and ~/.kube/config, and falls back to the in-cluster
+// service account. like $KUBECONFIG, kubeconfig may list several files to
+// merge. if no credentials are found, the returned error satisfies
+// clientcmd.IsEmptyConfig.
+func Config(kubeconfig, context string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if paths := filepath.SplitList(kubeconfig); len(paths) > 1 {
+		rules.Precedence = paths
+	} else {
+		rules.ExplicitPath = kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.QPS = qps
+	config.Burst = burst
+	return config, nil
+}
+
+// InClusterConfig returns the configuration of a client for the api server
+// that uses the service account of the pod snowcat runs in. outside of a
+// cluster, the returned error is rest.ErrNotInCluster.
+func InClusterConfig() (*rest.Config, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.QPS = qps
+	config.Burst = burst
+	return config, nil
+}
+
+// Collector lists resources from the api server.
+type Collector struct {
+	dynamic  dynamic.Interface
+	metadata metadata.Interface
+
+	// PageSize is the number of objects requested per page of a list.
+	PageSize int64
+}
+
+// NewCollector creates a collector for the api server of config.
+func NewCollector(config *rest.Config) (*Collector, error) {
+	dyn, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return newCollector(dyn, meta), nil
+}
+
+func newCollector(dyn dynamic.Interface, meta metadata.Interface) *Collector {
+	return &Collector{
+		dynamic:  dyn,
+		metadata: meta,
+		PageSize: DefaultPageSize,
+	}
+}
+
+// Report summarizes a collection.
+type Report struct {
+	// Collected is the number of objects listed of each kind.
+	Collected map[string]int
+	// Forbidden lists the kinds that the credentials may not list.
+	Forbidden []string
+	// Missing lists the kinds that the api server does not serve.
+	Missing []string
+}
+
+// Collect lists every supported kind across all namespaces and loads the
+// objects into resources. the returned error holds the failures other than
+// forbidden and missing kinds, which are recorded in the report instead.
+func (c *Collector) Collect(ctx context.Context, resources *types.Resources) (Report, error) {
+	report := Report{Collected: make(map[string]int)}
+	var errs error
+
+	for _, k := range kinds {
+		objs, err := c.list(ctx, k)
+		switch {
+		case apierrors.IsForbidden(err):
+			log.WithFields(log.Fields{
+				"kind": k.String(),
+			}).Warn("forbidden from listing kind")
+			report.Forbidden = append(report.Forbidden, k.String())
+			continue
+		case apierrors.IsNotFound(err):
+			log.WithFields(log.Fields{
+				"kind": k.String(),
+			}).Debug("kind not served by api server")
+			report.Missing = append(report.Missing, k.String())
+			continue
+		case err != nil:
+			log.WithFields(log.Fields{
+				"kind": k.String(),
+				"err":  err,
+			}).Warn("failed to list kind")
+			errs = multierror.Append(errs, fmt.Errorf("%s: %w", k, err))
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"kind":  k.String(),
+			"count": len(objs),
+		}).Debug("listed kind")
+		report.Collected[k.String()] = len(objs)
+		resources.Load(objs)
+	}
+
+	return report, errs
+}
+
+// list lists the objects of a kind in the first version that the api server
+// serves.
+func (c *Collector) list(ctx context.Context, k kind) ([]runtime.Object, error) {
+	var err error
+	for _, version := range k.versions {
+		gvr := schema.GroupVersionResource{Group: k.group, Version: version, Resource: k.resource}
+
+		var objs []runtime.Object
+		if k.metadataOnly {
+			objs, err = c.listMetadata(ctx, gvr)
+		} else {
+			objs, err = c.listObjects(ctx, gvr)
+		}
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		return objs, err
+	}
+	return nil, err
+}
+
+// listObjects lists every page of a resource and decodes the objects into
+// the types known to types.Resources.
+func (c *Collector) listObjects(ctx context.Context, gvr schema.GroupVersionResource) ([]runtime.Object, error) {
+	decoder := clientsetscheme.Codecs.UniversalDeserializer()
+	p := pager.New(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.dynamic.Resource(gvr).List(ctx, opts)
+	})
+	p.PageSize = c.PageSize
+
+	var objs []runtime.Object
+	err := p.EachListItem(ctx, metav1.ListOptions{}, func(item runtime.Object) error {
+		u, ok := item.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected list item %T", item)
+		}
+		data, err := u.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		obj, _, err := decoder.Decode(data, nil, nil)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"kind":     u.GroupVersionKind().String(),
+				"resource": u.GetNamespace() + ":" + u.GetName(),
+				"err":      err,
+			}).Warn("failed to decode resource")
+			return nil
+		}
+		objs = append(objs, obj)
+		return nil
+	})
+	return objs, err
+}
+
+// listMetadata lists every page of the metadata of a resource. only secrets
+// are listed this way, so the objects are returned as secrets.
+func (c *Collector) listMetadata(ctx context.Context, gvr schema.GroupVersionResource) ([]runtime.Object, error) {
+	p := pager.New(func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+		return c.metadata.Resource(gvr).List(ctx, opts)
+	})
+	p.PageSize = c.PageSize
+
+	var objs []runtime.Object
+	err := p.EachListItem(ctx, metav1.ListOptions{}, func(item runtime.Object) error {
+		m, ok := item.(*metav1.PartialObjectMetadata)
+		if !ok {
+			return fmt.Errorf("unexpected list item %T", item)
+		}
+		objs = append(objs, &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: m.ObjectMeta,
+		})
+		return nil
+	})
+	return objs, err
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"k8s.io/client-go/rest"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// status writes a kubernetes Status response with the code.
+func status(w http.ResponseWriter, code int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":%q,"code":%d}`, reason, code)
+}
+
+// fakeAPIServer serves pods in pages of one, secret metadata, authorization
+// policies in v1beta1 only, and forbids listing configmaps. every other kind
+// is not found.
+func fakeAPIServer(t *testing.T) *httptest.Server {
+	pods := []string{"httpbin", "sleep"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/pods", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1", r.URL.Query().Get("limit"))
+		page := 0
+		if r.URL.Query().Get("continue") != "" {
+			page = 1
+		}
+		cont := ""
+		if page == 0 {
+			cont = "page-1"
+		}
+		list := map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "PodList",
+			"metadata":   map[string]interface{}{"continue": cont},
+			"items": []interface{}{
+				map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Pod",
+					"metadata":   map[string]interface{}{"name": pods[page], "namespace": "apps"},
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	})
+	mux.HandleFunc("/api/v1/secrets", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"meta.k8s.io/v1","kind":"PartialObjectMetadataList","metadata":{},"items":[
+			{"apiVersion":"meta.k8s.io/v1","kind":"PartialObjectMetadata","metadata":{"name":"token","namespace":"apps"}}]}`)
+	})
+	mux.HandleFunc("/api/v1/configmaps", func(w http.ResponseWriter, r *http.Request) {
+		status(w, http.StatusForbidden, "Forbidden")
+	})
+	mux.HandleFunc("/apis/security.istio.io/v1beta1/authorizationpolicies", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"security.istio.io/v1beta1","kind":"AuthorizationPolicyList","metadata":{},"items":[
+			{"apiVersion":"security.istio.io/v1beta1","kind":"AuthorizationPolicy",
+			 "metadata":{"name":"deny-admin","namespace":"apps"},"spec":{"action":"DENY"}}]}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		status(w, http.StatusNotFound, "NotFound")
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCollect(t *testing.T) {
+	srv := fakeAPIServer(t)
+	defer srv.Close()
+
+	c, err := NewCollector(&rest.Config{Host: srv.URL, QPS: qps, Burst: burst})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.PageSize = 1
+
+	resources := types.NewResources()
+	report, err := c.Collect(context.Background(), &resources)
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, []string{"configmaps"}, report.Forbidden)
+	assert.Equal(t, 2, report.Collected["pods"])
+	assert.Equal(t, 1, report.Collected["secrets"])
+	assert.Equal(t, 1, report.Collected["authorizationpolicies.security.istio.io"])
+	assert.Equal(t, len(kinds)-4, len(report.Missing))
+
+	var names []string
+	for _, pod := range resources.Pods {
+		names = append(names, pod.Name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"httpbin", "sleep"}, names)
+
+	assert.Equal(t, 1, len(resources.Secrets))
+	assert.Equal(t, "token", resources.Secrets[0].Name)
+	assert.Equal(t, 1, len(resources.AuthorizationPolicies))
+	assert.Equal(t, "DENY", resources.AuthorizationPolicies[0].Spec.Action.String())
+}
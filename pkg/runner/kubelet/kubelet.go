@@ -25,6 +25,7 @@
 package kubelet
 
 import (
+	"context"
 	"net"
 	"time"
 
@@ -44,10 +45,11 @@ var Runner = runner.Runner{
 	Strategies: []runner.Strategy{
 		&defaultGatewayStrategy{},
 	},
+	Provides: []runner.Field{runner.KubeletAddresses},
 }
 
-func verifyKubeletAPI(addr string) bool {
-	_, err := kubelet.NewClient(addr)
+func verifyKubeletAPI(ctx context.Context, clients runner.Clients, addr string) bool {
+	_, err := kubelet.NewClient(ctx, addr, clients.Kubelet)
 	return err == nil
 }
 
@@ -60,7 +62,7 @@ func (s *defaultGatewayStrategy) Name() string {
 
 // Run executes the default gateway strategy and populates the Discovery type's
 // KubeletAddress if it can verify the results.
-func (s *defaultGatewayStrategy) Run(input *types.Discovery) error {
+func (s *defaultGatewayStrategy) Run(ctx context.Context, clients runner.Clients, input *types.Discovery) error {
 	var hosts []string
 
 	log.Info("attempting to locate default gateway")
@@ -92,7 +94,10 @@ func (s *defaultGatewayStrategy) Run(input *types.Discovery) error {
 	var results []string
 
 	for addr := range scanner.Scan(500 * time.Millisecond) {
-		if verifyKubeletAPI(addr) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if verifyKubeletAPI(ctx, clients, addr) {
 			log.WithFields(log.Fields{
 				"addr": addr,
 			}).Debug("discovered kubelet api")
@@ -28,6 +28,7 @@
 package namespace
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 
@@ -44,6 +45,7 @@ var Runner = runner.Runner{
 		&envoyStrategy{},
 		&defaultStrategy{},
 	},
+	Provides: []runner.Field{runner.IstioNamespace},
 }
 
 func verifyIstioNamespace(addr string) error {
@@ -62,7 +64,7 @@ func (s *defaultStrategy) Name() string {
 
 // Run executes the default strategy and populates the Discovery type's
 // IstioNamespace if it can verify the results.
-func (s *defaultStrategy) Run(input *types.Discovery) error {
+func (s *defaultStrategy) Run(ctx context.Context, clients runner.Clients, input *types.Discovery) error {
 	// istiod.istio-system.svc.cluster.local:15010
 	ns := "istio-system"
 
@@ -85,9 +87,9 @@ func (s *envoyStrategy) Name() string {
 
 // Run executes the envoy strategy and populates the Discovery type's
 // IstioNamespace if it can verify the results.
-func (s *envoyStrategy) Run(input *types.Discovery) error {
+func (s *envoyStrategy) Run(ctx context.Context, clients runner.Clients, input *types.Discovery) error {
 	// curl -s 127.0.0.1:15000/server_info | jq -r .node.metadata.PROXY_CONFIG.discoveryAddress
-	ec, err := envoy.RetrieveConfig("http://localhost:15000/config_dump")
+	ec, err := envoy.RetrieveConfig(ctx, "http://localhost:15000/config_dump", clients.Envoy)
 	if err != nil {
 		return err
 	}
@@ -97,11 +99,11 @@ func (s *envoyStrategy) Run(input *types.Discovery) error {
 		return err
 	}
 
-	r := regexp.MustCompile(`istiod\.(.*)\.svc.*:.*`)
+	r := regexp.MustCompile(`istiod(?:-[^.]+)?\.(.*)\.svc.*:.*`)
 
 	match := r.FindStringSubmatch(address)
 	if match == nil {
-		return fmt.Errorf("%s did not match expected regex of `istiod.<namespace>.svc:<port>`", address)
+		return fmt.Errorf("%s did not match expected regex of `istiod[-<revision>].<namespace>.svc:<port>`", address)
 	}
 
 	err = verifyIstioNamespace(match[1])
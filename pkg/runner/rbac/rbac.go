@@ -0,0 +1,236 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rbac implements a runner to probe the permissions of the service
+// account token mounted in the workload running snowcat. from the perspective
+// of an attacker that has compromised the workload, this answers what the
+// token can do. it comes equipped with the following strategies:
+//
+// ServiceAccountStrategy:
+//    reads the token mounted at /var/run/secrets/kubernetes.io/serviceaccount,
+//    submits a SelfSubjectRulesReview for the namespace of the service account,
+//    and a SelfSubjectAccessReview for each sensitive verb and resource, both
+//    in that namespace and across all namespaces.
+package rbac
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/praetorian-inc/snowcat/pkg/runner"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// tokenDir is where kubernetes mounts the service account token.
+const tokenDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Runner defines the list of strategies to use to probe the permissions of
+// the workload.
+var Runner = runner.Runner{
+	Name: "RBAC",
+	Strategies: []runner.Strategy{
+		&serviceAccountStrategy{dir: tokenDir},
+	},
+	Provides: []runner.Field{runner.RBAC},
+}
+
+// check is a sensitive verb on a resource to review.
+type check struct {
+	verb        string
+	group       string
+	resource    string
+	subresource string
+	// namespaced checks are reviewed in the namespace of the service account
+	// as well as across all namespaces.
+	namespaced bool
+}
+
+// checks are the sensitive verbs and resources to review. they cover reading
+// credentials, running code in other workloads, intercepting admission, and
+// changing the security configuration of the mesh.
+var checks = []check{
+	{verb: "get", resource: "secrets", namespaced: true},
+	{verb: "list", resource: "secrets", namespaced: true},
+	{verb: "create", resource: "pods", namespaced: true},
+	{verb: "create", resource: "pods", subresource: "exec", namespaced: true},
+	{verb: "create", resource: "serviceaccounts", subresource: "token", namespaced: true},
+	{verb: "impersonate", resource: "serviceaccounts", namespaced: true},
+	{verb: "create", group: "rbac.authorization.k8s.io", resource: "rolebindings", namespaced: true},
+	{verb: "create", group: "rbac.authorization.k8s.io", resource: "clusterrolebindings"},
+	{verb: "escalate", group: "rbac.authorization.k8s.io", resource: "clusterroles"},
+	{verb: "create", group: "admissionregistration.k8s.io", resource: "mutatingwebhookconfigurations"},
+	{verb: "patch", group: "admissionregistration.k8s.io", resource: "mutatingwebhookconfigurations"},
+	{verb: "delete", group: "admissionregistration.k8s.io", resource: "validatingwebhookconfigurations"},
+	{verb: "create", group: "networking.istio.io", resource: "envoyfilters", namespaced: true},
+	{verb: "create", group: "extensions.istio.io", resource: "wasmplugins", namespaced: true},
+	{verb: "create", group: "networking.istio.io", resource: "virtualservices", namespaced: true},
+	{verb: "create", group: "networking.istio.io", resource: "destinationrules", namespaced: true},
+	{verb: "create", group: "networking.istio.io", resource: "sidecars", namespaced: true},
+	{verb: "create", group: "security.istio.io", resource: "authorizationpolicies", namespaced: true},
+	{verb: "delete", group: "security.istio.io", resource: "authorizationpolicies", namespaced: true},
+	{verb: "create", group: "security.istio.io", resource: "peerauthentications", namespaced: true},
+	{verb: "delete", group: "security.istio.io", resource: "peerauthentications", namespaced: true},
+}
+
+type serviceAccountStrategy struct {
+	// dir is the directory holding the token, namespace and ca.crt files.
+	dir string
+	// host is the address of the api server, which defaults to the one
+	// given by the in-cluster environment.
+	host string
+}
+
+// Name returns the strategy name for reporting purposes.
+func (s *serviceAccountStrategy) Name() string {
+	return "service-account"
+}
+
+// Run executes the service account strategy and populates the Discovery
+// type's RBAC with the permissions of the mounted token.
+func (s *serviceAccountStrategy) Run(ctx context.Context, clients runner.Clients, input *types.Discovery) error {
+	token, err := os.ReadFile(filepath.Join(s.dir, "token"))
+	if err != nil {
+		return err
+	}
+	namespace, err := os.ReadFile(filepath.Join(s.dir, "namespace"))
+	if err != nil {
+		return err
+	}
+
+	host := s.host
+	if host == "" {
+		h, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if h == "" || port == "" {
+			return fmt.Errorf("kubernetes api address not found in environment")
+		}
+		host = "https://" + net.JoinHostPort(h, port)
+	}
+
+	config := &rest.Config{
+		Host:            host,
+		BearerToken:     strings.TrimSpace(string(token)),
+		TLSClientConfig: rest.TLSClientConfig{CAFile: filepath.Join(s.dir, "ca.crt")},
+		// a review is sent for each check in quick succession
+		QPS:   50,
+		Burst: 100,
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	rbac, err := Probe(ctx, client, strings.TrimSpace(string(namespace)))
+	if err != nil {
+		return err
+	}
+	rbac.ServiceAccount = serviceAccount(config.BearerToken, strings.TrimSpace(string(namespace)))
+	input.RBAC = rbac
+	return nil
+}
+
+// serviceAccount returns the "namespace:name" of the service account from
+// the subject of its token, or just the namespace if the token cannot be
+// parsed. the signature is not verified, since the api server already
+// accepted the token.
+func serviceAccount(token, namespace string) string {
+	const prefix = "system:serviceaccount:"
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return namespace + ":"
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return namespace + ":"
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || !strings.HasPrefix(claims.Subject, prefix) {
+		return namespace + ":"
+	}
+	return strings.TrimPrefix(claims.Subject, prefix)
+}
+
+// Probe reviews the rules of the client's credentials in namespace, and
+// whether they allow each sensitive verb and resource.
+func Probe(ctx context.Context, client kubernetes.Interface, namespace string) (*types.RBAC, error) {
+	rbac := &types.RBAC{}
+
+	rules, err := client.AuthorizationV1().SelfSubjectRulesReviews().Create(ctx, &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		log.WithFields(log.Fields{
+			"namespace": namespace,
+			"err":       err,
+		}).Warn("failed to review rules")
+	} else {
+		rbac.Rules = rules.Status.ResourceRules
+		rbac.Incomplete = rules.Status.Incomplete
+	}
+
+	for _, c := range checks {
+		namespaces := []string{""}
+		if c.namespaced {
+			namespaces = []string{namespace, ""}
+		}
+
+		for _, ns := range namespaces {
+			review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace:   ns,
+						Verb:        c.verb,
+						Group:       c.group,
+						Resource:    c.resource,
+						Subresource: c.subresource,
+					},
+				},
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("access review failed: %w", err)
+			}
+
+			permission := types.Permission{
+				Verb:        c.verb,
+				Group:       c.group,
+				Resource:    c.resource,
+				Subresource: c.subresource,
+				Namespace:   ns,
+				Allowed:     review.Status.Allowed,
+			}
+			if permission.Allowed {
+				log.WithFields(log.Fields{
+					"permission": permission.String(),
+				}).Info("service account is allowed sensitive access")
+			}
+			rbac.Permissions = append(rbac.Permissions, permission)
+		}
+	}
+
+	return rbac, nil
+}
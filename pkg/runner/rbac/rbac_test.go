@@ -0,0 +1,145 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	authorizationv1 "k8s.io/api/authorization/v1"
+
+	"github.com/praetorian-inc/snowcat/pkg/runner"
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// fakeAPIServer answers reviews for a service account that may read secrets
+// in its own namespace and create envoy filters anywhere.
+func fakeAPIServer(t *testing.T, token string) *httptest.Server {
+	allowed := map[types.Permission]bool{
+		{Verb: "get", Resource: "secrets", Namespace: "apps"}:                                       true,
+		{Verb: "create", Group: "networking.istio.io", Resource: "envoyfilters"}:                    true,
+		{Verb: "create", Group: "networking.istio.io", Resource: "envoyfilters", Namespace: "apps"}: true,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/apis/authorization.k8s.io/v1/selfsubjectrulesreviews", func(w http.ResponseWriter, r *http.Request) {
+		var review authorizationv1.SelfSubjectRulesReview
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "apps", review.Spec.Namespace)
+		review.Status.ResourceRules = []authorizationv1.ResourceRule{
+			{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"secrets"}},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(review)
+	})
+	mux.HandleFunc("/apis/authorization.k8s.io/v1/selfsubjectaccessreviews", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer "+token, r.Header.Get("Authorization"))
+		var review authorizationv1.SelfSubjectAccessReview
+		if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+			t.Fatal(err)
+		}
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = allowed[types.Permission{
+			Verb:        attrs.Verb,
+			Group:       attrs.Group,
+			Resource:    attrs.Resource,
+			Subresource: attrs.Subresource,
+			Namespace:   attrs.Namespace,
+		}]
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(review)
+	})
+	return httptest.NewTLSServer(mux)
+}
+
+func TestServiceAccountStrategy(t *testing.T) {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"system:serviceaccount:apps:httpbin"}`))
+	token := "eyJhbGciOiJSUzI1NiJ9." + claims + ".c2lnbmF0dXJl"
+
+	srv := fakeAPIServer(t, token)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	files := map[string][]byte{
+		"token":     []byte(token),
+		"namespace": []byte("apps"),
+		"ca.crt":    ca,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &serviceAccountStrategy{dir: dir, host: srv.URL}
+	var disco types.Discovery
+	if err := s.Run(context.Background(), runner.Clients{}, &disco); err != nil {
+		t.Fatal(err)
+	}
+
+	rbac := disco.RBAC
+	assert.Equal(t, "apps:httpbin", rbac.ServiceAccount)
+	assert.Equal(t, 1, len(rbac.Rules))
+
+	var granted []string
+	for _, p := range rbac.Permissions {
+		if p.Allowed {
+			granted = append(granted, p.String())
+		}
+	}
+	assert.Equal(t, []string{
+		"get secrets in apps",
+		"create envoyfilters.networking.istio.io in apps",
+		"create envoyfilters.networking.istio.io",
+	}, granted)
+}
+
+func TestServiceAccountStrategyOutsideCluster(t *testing.T) {
+	s := &serviceAccountStrategy{dir: t.TempDir()}
+	var disco types.Discovery
+	assert.NotEqual(t, nil, s.Run(context.Background(), runner.Clients{}, &disco))
+	assert.Equal(t, (*types.RBAC)(nil), disco.RBAC)
+}
+
+func TestServiceAccount(t *testing.T) {
+	type testcase struct {
+		token    string
+		expected string
+	}
+
+	sub := func(s string) string {
+		return "e30." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"`+s+`"}`)) + ".sig"
+	}
+
+	testcases := []testcase{
+		{sub("system:serviceaccount:apps:httpbin"), "apps:httpbin"},
+		{sub("admin"), "apps:"},
+		{"opaque-token", "apps:"},
+	}
+	for _, tc := range testcases {
+		assert.Equal(t, tc.expected, serviceAccount(tc.token, "apps"))
+	}
+}
@@ -25,18 +25,23 @@
 //
 // to perform a collection, a consuming package will need to construct a Runners
 // struct, containing the list of individual runners desired in the collection,
-// and call the Run() method on it.
+// and call the Run() method on it. each runner declares the Discovery fields it
+// requires and provides, so runners are ordered by their dependencies rather
+// than by their position in the list, and independent runners run concurrently.
 package runner
 
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/praetorian-inc/snowcat/pkg/debugz"
+	"github.com/praetorian-inc/snowcat/pkg/envoy"
 	kubeletclient "github.com/praetorian-inc/snowcat/pkg/kubelet"
 	"github.com/praetorian-inc/snowcat/pkg/types"
 	"github.com/praetorian-inc/snowcat/pkg/xds"
@@ -46,6 +51,22 @@ import (
 type Runner struct {
 	Name       string
 	Strategies []Strategy
+	// Requires lists the Discovery fields that the strategies use when they
+	// are known. The runner is run after the runners that provide them, and
+	// run again if one is only filled in after it started.
+	Requires []Field
+	// Provides lists the Discovery fields that the strategies fill in. Changes
+	// made to any other field are discarded.
+	Provides []Field
+}
+
+// Clients holds the options of the clients that strategies and runners
+// connect to the cluster with.
+type Clients struct {
+	XDS     xds.Options
+	Debugz  debugz.Options
+	Kubelet kubeletclient.Options
+	Envoy   envoy.Options
 }
 
 // Strategy is an interface that abstractly describes how information is to be
@@ -57,101 +78,118 @@ type Strategy interface {
 	// of collection. it is passed a reference to a types.Discovery struct to record
 	// gathered and verified data. it is assumed that the implementation of this
 	// function will perform validation of discovered data before recording it to
-	// the Discovery struct.
-	Run(input *types.Discovery) error
+	// the Discovery struct. implementations are expected to connect with the
+	// given clients options and to give up once ctx is done.
+	Run(ctx context.Context, clients Clients, input *types.Discovery) error
 }
 
 // Run as defined for a runner loops over all strategies and passes a
 // *types.Discovery. it then surfaces any errors it receives. if all strategies
-// fail, an error is produced
-func (r *Runner) Run(input *types.Discovery) error {
+// fail, an error is produced. no further strategy is tried once ctx is done.
+func (r *Runner) Run(ctx context.Context, clients Clients, input *types.Discovery) error {
+	_, err := r.run(ctx, clients, input)
+	return err
+}
+
+// trace is what a runner recorded about its strategies.
+type trace struct {
+	attempts []types.Attempt
+	// sources holds the strategy that last changed each field.
+	sources map[Field]types.FieldSource
+}
+
+// run runs the strategies like Run, and records each attempt and the
+// strategy that changed each field.
+func (r *Runner) run(ctx context.Context, clients Clients, input *types.Discovery) (trace, error) {
+	t := trace{sources: make(map[Field]types.FieldSource)}
 	var errs error
 	for _, strategy := range r.Strategies {
+		if ctx.Err() != nil {
+			errs = multierror.Append(errs, ctx.Err())
+			break
+		}
 		log.WithFields(log.Fields{
 			"runner":   r.Name,
 			"strategy": strategy.Name(),
 		}).Info("running discovery strategy")
-		err := strategy.Run(input)
+
+		before := cloneDiscovery(input)
+		attempt := types.Attempt{
+			Runner:   r.Name,
+			Strategy: strategy.Name(),
+			Started:  time.Now(),
+		}
+		err := strategy.Run(ctx, clients, input)
+		attempt.Finished = time.Now()
+		for _, f := range Fields {
+			if !reflect.DeepEqual(f.value(before), f.value(input)) {
+				t.sources[f] = types.FieldSource{
+					Field:  string(f),
+					Source: types.StrategySource(r.Name, strategy.Name()),
+					Time:   attempt.Finished,
+				}
+			}
+		}
 		if err != nil {
+			attempt.Error = err.Error()
+			t.attempts = append(t.attempts, attempt)
 			errs = multierror.Append(errs, fmt.Errorf("%s: %w", strategy.Name(), err))
 			continue
 		}
-		return nil
+		t.attempts = append(t.attempts, attempt)
+		return t, nil
 	}
-	return fmt.Errorf("all strategies failed: %s", errs)
+	return t, fmt.Errorf("all strategies failed: %s", errs)
 }
 
 // Runners defines a type alias for a list of Runner structs
 type Runners []Runner
 
-// Run as defined for a Runners type iterates over all runners in the list, and
-// calls each runner's Run() method. it then looks at the resulting
-// types.Discovery and attempts to use them to confirm if they are correct.
-func (runners Runners) Run(disco *types.Discovery, resources *types.Resources) {
-	ctx := context.Background()
+// Run as defined for a Runners type runs every runner in the list, ordered by
+// the fields they require and provide, and calls each runner's Run() method.
+// it then looks at the resulting types.Discovery and attempts to use them to
+// confirm if they are correct, collecting config from the istiod of every
+// discovered revision. once ctx is done, no further runner or client is
+// started and the discoveries made so far are kept.
+func (runners Runners) Run(ctx context.Context, clients Clients, disco *types.Discovery, resources *types.Resources) {
+	runners.discover(ctx, clients, disco)
 
-	for _, r := range runners {
-		err := r.Run(disco)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"runner": r.Name,
-				"err":    err,
-			}).Warn("failed to run")
+	// the version is the one reported by the istiod of the primary revision
+	versionSource := make(map[string]string)
+	if len(disco.Revisions) == 0 {
+		if version, source := collectIstiod(ctx, clients, types.DefaultRevision, serverName(disco, types.DefaultRevision), disco.DiscoveryAddress, disco.DebugzAddress, resources); version != "" {
+			disco.IstioVersion = version
+			versionSource[version] = source
 		}
 	}
-
-	if disco.DiscoveryAddress != "" {
-		cli, err := xds.NewClient(disco.DiscoveryAddress)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"addr": disco.DiscoveryAddress,
-				"err":  err,
-			}).Warn("failed initialize xds client")
-		}
-		res, err := cli.Resources(ctx)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"addr": disco.DiscoveryAddress,
-				"err":  err,
-			}).Warn("failed query xds resources")
+	for i := range disco.Revisions {
+		if ctx.Err() != nil {
+			break
 		}
-		resources.Load(res)
-		disco.IstioVersion, err = cli.Version(ctx)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"addr": disco.DiscoveryAddress,
-				"err":  err,
-			}).Warn("failed query xds version")
+		rev := &disco.Revisions[i]
+		log.WithFields(log.Fields{
+			"revision": rev.Name,
+		}).Info("collecting from control plane revision")
+		if version, source := collectIstiod(ctx, clients, rev.Name, serverName(disco, rev.Name), rev.DiscoveryAddress, rev.DebugzAddress, resources); version != "" {
+			rev.IstioVersion = version
+			versionSource[version] = source
 		}
-		cli.Close()
 	}
-	if disco.DebugzAddress != "" {
-		cli, err := debugz.NewClient(disco.DebugzAddress)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"addr": disco.DebugzAddress,
-				"err":  err,
-			}).Warn("failed initialize debugz client")
-		}
-		res, err := cli.Resources(ctx)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"addr": disco.DebugzAddress,
-				"err":  err,
-			}).Warn("failed query debugz resources")
-		}
-		disco.IstioVersion, err = cli.Version(ctx)
-		if err != nil {
-			log.WithFields(log.Fields{
-				"addr": disco.DebugzAddress,
-				"err":  err,
-			}).Warn("failed query debugz version")
-		}
-		resources.Load(res)
+	disco.SetPrimaryRevision()
+	// the mesh config of the primary revision stands for the whole mesh
+	if resources.MeshConfig == nil {
+		resources.MeshConfig = resources.RevisionMeshConfig(disco.PrimaryRevision())
+	}
+	if source, ok := versionSource[disco.IstioVersion]; ok {
+		disco.Provenance.SetField(string(IstioVersion), source, time.Now())
 	}
+
 	if len(disco.KubeletAddresses) > 0 {
 		for _, addr := range disco.KubeletAddresses {
-			cli, err := kubeletclient.NewClient(addr)
+			if ctx.Err() != nil {
+				break
+			}
+			cli, err := kubeletclient.NewClient(ctx, addr, clients.Kubelet)
 			if err != nil {
 				log.WithFields(log.Fields{
 					"addr": addr,
@@ -174,4 +212,88 @@ func (runners Runners) Run(disco *types.Discovery, resources *types.Resources) {
 			resources.Load(res)
 		}
 	}
+	disco.Provenance.Finished = time.Now()
+}
+
+// serverName returns the name that the certificate of a revision's istiod is
+// verified against when it was discovered by IP address, or an empty string
+// if the istio namespace is unknown.
+func serverName(disco *types.Discovery, revision string) string {
+	if disco.IstioNamespace == "" {
+		return ""
+	}
+	return xds.ServiceName(revision, disco.IstioNamespace)
+}
+
+// collectIstiod loads the config served by the istiod of a revision at the
+// discovery and debug addresses, either of which may be empty, and returns its
+// version and the API that reported it. The secure xds of an IP address is
+// verified against serverName.
+func collectIstiod(ctx context.Context, clients Clients, revision, serverName, discoveryAddress, debugzAddress string, resources *types.Resources) (string, string) {
+	var version, source string
+	if discoveryAddress != "" {
+		opts := clients.XDS
+		opts.ServerName = serverName
+		// an unreachable xds leaves the debug API to collect from
+		if cli, err := xds.NewClient(ctx, discoveryAddress, opts); err != nil {
+			log.WithFields(log.Fields{
+				"addr": discoveryAddress,
+				"err":  err,
+			}).Warn("failed initialize xds client")
+		} else {
+			res, err := cli.Resources(ctx)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"addr": discoveryAddress,
+					"err":  err,
+				}).Warn("failed query xds resources")
+			}
+			resources.Load(res)
+			if v, err := cli.Version(ctx); err != nil {
+				log.WithFields(log.Fields{
+					"addr": discoveryAddress,
+					"err":  err,
+				}).Warn("failed query xds version")
+			} else {
+				version, source = v, types.SourceXDS
+			}
+			cli.Close()
+		}
+	}
+	if debugzAddress != "" && ctx.Err() == nil {
+		cli, err := debugz.NewClient(ctx, debugzAddress, clients.Debugz)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"addr": debugzAddress,
+				"err":  err,
+			}).Warn("failed initialize debugz client")
+			return version, source
+		}
+		res, err := cli.Resources(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"addr": debugzAddress,
+				"err":  err,
+			}).Warn("failed query debugz resources")
+		}
+		if v, err := cli.Version(ctx); err != nil {
+			log.WithFields(log.Fields{
+				"addr": debugzAddress,
+				"err":  err,
+			}).Warn("failed query debugz version")
+		} else {
+			version, source = v, types.SourceDebugz
+		}
+		resources.Load(res)
+		mesh, err := cli.MeshConfig(ctx)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"addr": debugzAddress,
+				"err":  err,
+			}).Warn("failed query debugz mesh config")
+		} else if mesh != nil {
+			resources.SetRevisionMeshConfig(revision, mesh)
+		}
+	}
+	return version, source
 }
@@ -0,0 +1,61 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+	"github.com/praetorian-inc/snowcat/pkg/xds"
+)
+
+func TestCollectIstiodWithoutXDS(t *testing.T) {
+	debug := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/debug/configz":
+			w.Write([]byte("[]")) // nolint:errcheck
+		case "/debug/syncz":
+			w.Write([]byte(`[{"istio_version": "1.14.1", "proxy": "app"}]`)) // nolint:errcheck
+		case "/debug/mesh":
+			w.Write([]byte("rootNamespace: istio-config\n")) // nolint:errcheck
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer debug.Close()
+
+	// a closed port stands in for an unreachable xds
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	discoveryAddress := lis.Addr().String()
+	lis.Close()
+
+	clients := Clients{XDS: xds.Options{Timeout: 500 * time.Millisecond}}
+	resources := &types.Resources{}
+	version, source := collectIstiod(context.Background(), clients, "canary", "", discoveryAddress, strings.TrimPrefix(debug.URL, "http://"), resources)
+	assert.Equal(t, "1.14.1", version)
+	assert.Equal(t, types.SourceDebugz, source)
+	assert.Equal(t, "istio-config", resources.RevisionMeshConfig("canary").GetRootNamespace())
+}
@@ -0,0 +1,258 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package snapshot implements snapshot bundles, single files that hold
+// everything needed to repeat an audit offline: the facts learned during
+// discovery, metadata about the collection, and every collected resource.
+//
+// A bundle is a gzipped tar archive. Its first entry is snapshot.json, which
+// holds the Manifest, followed by the resources as YAML lists under
+// resources/, in the same form that Resources.Export writes them, and the
+// mesh config as mesh.yaml.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const (
+	// FormatVersion is the version of the bundle format written by Write.
+	// Bundles of later versions are rejected by Read.
+	FormatVersion = 1
+
+	manifestName   = "snapshot.json"
+	meshConfigName = "mesh.yaml"
+	resourcesDir   = "resources"
+
+	// revisionMeshConfigPrefix and revisionMeshConfigSuffix surround the
+	// revision in the name of the mesh config that its istiod served.
+	revisionMeshConfigPrefix = "mesh-"
+	revisionMeshConfigSuffix = ".yaml"
+)
+
+// ErrNotBundle is returned by Read if the file is not a snapshot bundle.
+var ErrNotBundle = errors.New("not a snapshot bundle")
+
+const (
+	// ModeCluster is the mode of snapshots collected from a live cluster.
+	ModeCluster = "cluster"
+	// ModeFiles is the mode of snapshots loaded from manifests.
+	ModeFiles = "files"
+)
+
+// Source describes where the resources of a snapshot were collected.
+type Source struct {
+	// Mode is either ModeCluster or ModeFiles.
+	Mode string `json:"mode"`
+	// Input is the path that resources were loaded from in ModeFiles.
+	Input string `json:"input,omitempty"`
+	// Hostname is the name of the host that collected the snapshot.
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// Manifest describes the contents of a bundle.
+type Manifest struct {
+	// Version is the format version of the bundle.
+	Version int `json:"version"`
+	// CollectionStarted and CollectionFinished bound the time during which
+	// the discovery and resources were collected.
+	CollectionStarted  time.Time `json:"collectionStarted"`
+	CollectionFinished time.Time `json:"collectionFinished"`
+	// Source describes where the resources were collected.
+	Source Source `json:"source"`
+	// Discovery holds the facts learned during discovery.
+	Discovery types.Discovery `json:"discovery"`
+	// Resources is the number of resources in the bundle.
+	Resources int `json:"resources"`
+}
+
+// Snapshot is the content of a bundle.
+type Snapshot struct {
+	Manifest  Manifest
+	Resources types.Resources
+}
+
+// Write writes a bundle of the manifest and resources to w. The version and
+// resource count of the manifest are set by Write.
+func Write(w io.Writer, manifest Manifest, resources types.Resources) error {
+	manifest.Version = FormatVersion
+	manifest.Resources = resources.Len()
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		err := archive.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			ModTime:  manifest.CollectionFinished,
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = archive.Write(data)
+		return err
+	}
+
+	if err := add(manifestName, data); err != nil {
+		return err
+	}
+	err = resources.ExportTo(func(name string, data []byte) error {
+		return add(path.Join(resourcesDir, name), data)
+	})
+	if err != nil {
+		return err
+	}
+	// the mesh config is written after the resources so that it takes
+	// precedence over the istio ConfigMap when the bundle is read, as it
+	// may have been loaded from a file or the control plane instead
+	if resources.MeshConfig != nil {
+		mesh, err := types.EncodeMeshConfig(resources.MeshConfig)
+		if err != nil {
+			return err
+		}
+		if err := add(meshConfigName, mesh); err != nil {
+			return err
+		}
+	}
+	// those of revisions loaded from ConfigMaps are loaded again from them
+	for _, config := range resources.MeshConfigs() {
+		if config.Revision == "" || config.ConfigMap != "" {
+			continue
+		}
+		mesh, err := types.EncodeMeshConfig(config.MeshConfig)
+		if err != nil {
+			return err
+		}
+		if err := add(revisionMeshConfigPrefix+config.Revision+revisionMeshConfigSuffix, mesh); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// WriteFile writes a bundle to the named file.
+func WriteFile(name string, manifest Manifest, resources types.Resources) (err error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ferr := f.Close()
+		if ferr != nil && err == nil {
+			err = ferr
+		}
+	}()
+
+	return Write(f, manifest, resources)
+}
+
+// Read reads the bundle at the named path. Read returns ErrNotBundle if the
+// file is not a bundle, so that callers can fall back to other formats.
+func Read(name string) (*Snapshot, error) {
+	f, err := os.Open(name) // nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, ErrNotBundle
+	}
+	defer gz.Close()
+	archive := tar.NewReader(gz)
+
+	hdr, err := archive.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, ErrNotBundle
+	}
+
+	snapshot := &Snapshot{Resources: types.NewResources()}
+	if err := json.NewDecoder(archive).Decode(&snapshot.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid snapshot manifest: %w", err)
+	}
+	if snapshot.Manifest.Version > FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected at most %d",
+			snapshot.Manifest.Version, FormatVersion)
+	}
+
+	source := filepath.ToSlash(name)
+	for {
+		hdr, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if hdr.Name == meshConfigName {
+			data, err := io.ReadAll(archive)
+			if err != nil {
+				return nil, err
+			}
+			snapshot.Resources.MeshConfig, err = types.ParseMeshConfig(data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid snapshot mesh config: %w", err)
+			}
+			continue
+		}
+		if strings.HasPrefix(hdr.Name, revisionMeshConfigPrefix) && strings.HasSuffix(hdr.Name, revisionMeshConfigSuffix) {
+			revision := strings.TrimSuffix(strings.TrimPrefix(hdr.Name, revisionMeshConfigPrefix), revisionMeshConfigSuffix)
+			data, err := io.ReadAll(archive)
+			if err != nil {
+				return nil, err
+			}
+			mesh, err := types.ParseMeshConfig(data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid snapshot mesh config of revision %s: %w", revision, err)
+			}
+			snapshot.Resources.SetRevisionMeshConfig(revision, mesh)
+			continue
+		}
+		if !strings.HasPrefix(hdr.Name, resourcesDir+"/") {
+			continue
+		}
+		err = snapshot.Resources.LoadFromReader(path.Join(source, hdr.Name), archive)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
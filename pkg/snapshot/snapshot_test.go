@@ -0,0 +1,98 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+	meshv1alpha1 "istio.io/api/mesh/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+const snapshotYAML = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: httpbin
+  namespace: apps
+`
+
+func TestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	resources := types.NewResources()
+	if err := resources.LoadFromReader("serviceaccounts.yaml", strings.NewReader(snapshotYAML)); err != nil {
+		t.Fatal(err)
+	}
+	resources.MeshConfig = &meshv1alpha1.MeshConfig{RootNamespace: "istio-config"}
+	resources.SetRevisionMeshConfig("canary", &meshv1alpha1.MeshConfig{TrustDomain: "canary.example.com"})
+
+	finished := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	manifest := Manifest{
+		CollectionStarted:  finished.Add(-time.Minute),
+		CollectionFinished: finished,
+		Source:             Source{Mode: ModeCluster, Hostname: "workstation"},
+		Discovery: types.Discovery{
+			IstioVersion:     "1.13.0",
+			IstioNamespace:   "istio-system",
+			DiscoveryAddress: "10.0.0.1:15010",
+			KubeletAddresses: []string{"10.0.1.1:10255"},
+		},
+	}
+
+	name := filepath.Join(dir, "cluster.snowcat.tgz")
+	if err := WriteFile(name, manifest, resources); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := Read(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest.Version = FormatVersion
+	manifest.Resources = resources.Len()
+	assert.Equal(t, manifest, snap.Manifest)
+	assert.Equal(t, resources.Len(), snap.Resources.Len())
+
+	sa, ok := snap.Resources.Lookup("v1", "ServiceAccount", "apps:httpbin").(*corev1.ServiceAccount)
+	assert.T(t, ok)
+	assert.Equal(t, "httpbin", sa.Name)
+
+	sources := snap.Resources.Sources("v1", "ServiceAccount", "apps:httpbin")
+	assert.Equal(t, 1, len(sources))
+	assert.Equal(t, filepath.ToSlash(name)+"/resources/serviceaccounts.yaml", sources[0].File)
+
+	assert.Equal(t, "istio-config", snap.Resources.MeshConfig.GetRootNamespace())
+	assert.Equal(t, "canary.example.com", snap.Resources.RevisionMeshConfig("canary").GetTrustDomain())
+}
+
+func TestReadNotBundle(t *testing.T) {
+	dir := t.TempDir()
+
+	name := filepath.Join(dir, "serviceaccounts.yaml")
+	if err := os.WriteFile(name, []byte(snapshotYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Read(name)
+	assert.Equal(t, ErrNotBundle, err)
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Stdin is the path that LoadFromPath reads from standard input.
+const Stdin = "-"
+
+// LoadFromPath loads resources from a directory, a .tar, .tar.gz, .tgz or .zip
+// archive of manifests, a single manifest file, or standard input if path is
+// Stdin. Archives are loaded like directories, and a single file or standard
+// input is loaded regardless of its extension.
+func (r *Resources) LoadFromPath(name string) error {
+	if name == Stdin {
+		return r.LoadFromReader("stdin", os.Stdin)
+	}
+
+	stat, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		return r.LoadFromDirectory(name)
+	}
+
+	source := filepath.ToSlash(name)
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		archive, err := zip.OpenReader(name)
+		if err != nil {
+			return err
+		}
+		defer archive.Close()
+		return r.LoadFromFS(archive, source)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		f, err := os.Open(name) // nolint:gosec
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		defer gz.Close()
+		return r.loadTar(source, gz)
+	case strings.HasSuffix(lower, ".tar"):
+		f, err := os.Open(name) // nolint:gosec
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return r.loadTar(source, f)
+	default:
+		f, err := os.Open(name) // nolint:gosec
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return r.LoadFromReader(source, f)
+	}
+}
+
+// LoadFromReader loads the manifests read from in, recording their sources
+// under the given name.
+func (r *Resources) LoadFromReader(name string, in io.Reader) error {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	r.load(name, data)
+	return nil
+}
+
+// loadTar loads the YAML and JSON files of a tar archive, recording their
+// sources relative to root.
+func (r *Resources) loadTar(root string, in io.Reader) error {
+	archive := tar.NewReader(in)
+	for {
+		hdr, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", root, err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !isManifest(hdr.Name) {
+			continue
+		}
+		// skip the resource forks that macOS adds to archives
+		if strings.HasPrefix(path.Base(hdr.Name), "._") {
+			continue
+		}
+
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return fmt.Errorf("%s: %w", root, err)
+		}
+		r.load(path.Join(root, path.Clean(hdr.Name)), data)
+	}
+}
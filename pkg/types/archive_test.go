@@ -0,0 +1,112 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+const archiveYAML = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: httpbin
+  namespace: apps
+`
+
+func writeTar(t *testing.T, w io.Writer) {
+	archive := tar.NewWriter(w)
+	files := map[string]string{
+		"data/serviceaccounts.yaml":   archiveYAML,
+		"data/._serviceaccounts.yaml": "\x00\x05\x16\x07",
+		"data/notes.txt":              "not a manifest",
+	}
+	for name, data := range files {
+		err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFromPath(t *testing.T) {
+	dir := t.TempDir()
+	create := func(name string) *os.File {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	f := create("snapshot.tar")
+	writeTar(t, f)
+	f.Close()
+
+	f = create("snapshot.tar.gz")
+	gz := gzip.NewWriter(f)
+	writeTar(t, gz)
+	gz.Close()
+	f.Close()
+
+	f = create("snapshot.zip")
+	archive := zip.NewWriter(f)
+	w, err := archive.Create("data/serviceaccounts.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte(archiveYAML))
+	archive.Close()
+	f.Close()
+
+	// single files are loaded whatever their extension
+	f = create("manifest.txt")
+	_, _ = f.Write([]byte(archiveYAML))
+	f.Close()
+
+	testcases := []string{"snapshot.tar", "snapshot.tar.gz", "snapshot.zip", "manifest.txt"}
+	for _, name := range testcases {
+		resources := NewResources()
+		err := resources.LoadFromPath(filepath.Join(dir, name))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 1, len(resources.ServiceAccounts))
+
+		sources := resources.Sources("v1", "ServiceAccount", "apps:httpbin")
+		assert.Equal(t, 1, len(sources))
+		assert.Equal(t, true, strings.HasPrefix(sources[0].File, filepath.ToSlash(filepath.Join(dir, name))))
+	}
+}
+
+func TestLoadFromReader(t *testing.T) {
+	resources := NewResources()
+	err := resources.LoadFromReader("stdin", strings.NewReader(archiveYAML))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(resources.ServiceAccounts))
+	assert.Equal(t, "stdin:1", resources.Sources("v1", "ServiceAccount", "apps:httpbin")[0].String())
+}
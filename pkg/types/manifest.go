@@ -0,0 +1,187 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// manifestExtensions are the extensions of files that may hold manifests.
+var manifestExtensions = map[string]struct{}{
+	".yaml": {},
+	".yml":  {},
+	".json": {},
+}
+
+// Source is the location of an object within a manifest file.
+type Source struct {
+	// File is the path of the manifest file.
+	File string `json:"file"`
+	// Line is the line of the file that the object starts on, counting
+	// from one.
+	Line int `json:"line"`
+}
+
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// document is a single document of a YAML stream and the line it starts on.
+type document struct {
+	data []byte
+	line int
+}
+
+// isContent returns true if the line is neither blank nor a comment.
+func isContent(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	return len(line) > 0 && line[0] != '#'
+}
+
+// lineAt returns the line of data that offset is on, counting from one.
+func lineAt(data []byte, offset int) int {
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+// splitDocuments splits a manifest file into its documents, which are the
+// objects of a JSON stream or the documents of a YAML stream as read by the
+// Kubernetes YAML reader. Documents without content are dropped, and each
+// document starts at its first line of content. The documents read before an
+// error are returned along with it.
+func splitDocuments(data []byte) ([]document, error) {
+	var docs []document
+	if utilyaml.IsJSONBuffer(data) {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			offset := int(dec.InputOffset())
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+				return docs, nil
+			} else if err != nil {
+				return docs, err
+			}
+			start := offset + len(data[offset:]) - len(bytes.TrimLeft(data[offset:], " \t\r\n"))
+			docs = append(docs, document{data: raw, line: lineAt(data, start)})
+		}
+	}
+
+	// the reader drops carriage returns, which would keep documents from
+	// being found in the file
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	offset := 0
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		} else if err != nil {
+			return docs, err
+		}
+
+		// the reader returns the lines between separators as they are,
+		// ending the last line of the file with a newline
+		start := documentOffset(data, offset, bytes.TrimSuffix(doc, []byte("\n")))
+		offset = start + len(doc) - 1
+		for _, line := range bytes.SplitAfter(doc, []byte("\n")) {
+			if isContent(line) {
+				docs = append(docs, document{data: doc, line: lineAt(data, start)})
+				break
+			}
+			start += len(line)
+		}
+	}
+}
+
+// documentOffset returns the offset of the document read from data after
+// offset, which starts at the beginning of a line.
+func documentOffset(data []byte, offset int, doc []byte) int {
+	for offset < len(data) {
+		i := bytes.Index(data[offset:], doc)
+		if i < 0 {
+			break
+		}
+		if offset+i == 0 || data[offset+i-1] == '\n' {
+			return offset + i
+		}
+		offset += i + 1
+	}
+	return offset
+}
+
+// isManifest returns true if the file may hold Kubernetes manifests.
+func isManifest(path string) bool {
+	_, ok := manifestExtensions[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// load decodes every document of a manifest file and loads the Kubernetes
+// objects among them, recording where each was found. Documents that are not
+// Kubernetes objects are skipped, and documents that fail to decode are
+// reported without stopping the load.
+func (r *Resources) load(file string, data []byte) {
+	defer func() { r.source = Source{} }()
+
+	docs, err := splitDocuments(data)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"file": file,
+			"err":  err,
+		}).Warn("failed to read manifest documents")
+	}
+	for _, doc := range docs {
+		fields := log.Fields{
+			"file": file,
+			"line": doc.line,
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(doc.data, &typeMeta); err != nil {
+			fields["err"] = err
+			log.WithFields(fields).Warn("failed to parse manifest")
+			continue
+		}
+		if typeMeta.APIVersion == "" || typeMeta.Kind == "" {
+			log.WithFields(fields).Debug("skipping document without apiVersion and kind")
+			continue
+		}
+
+		obj, _, err := r.decoder.Decode(doc.data, nil, nil)
+		if err != nil {
+			fields["err"] = err
+			if runtime.IsNotRegisteredError(err) {
+				log.WithFields(fields).Debug("skipping resource of unknown type")
+			} else {
+				log.WithFields(fields).Warn("failed to decode resource")
+			}
+			continue
+		}
+
+		r.source = Source{File: file, Line: doc.line}
+		r.Load([]runtime.Object{obj})
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	type testcase struct {
+		description string
+		data        string
+		docs        []string
+		lines       []int
+		err         bool
+	}
+	testcases := []testcase{
+		{
+			description: "yaml stream",
+			data: `# leading comment
+---
+a: 1
+b: |
+  text with --- inside
+---
+
+# comment before content
+c: 2
+--- # trailing comment
+`,
+			docs:  []string{"a: 1\nb: |\n  text with --- inside\n", "\n# comment before content\nc: 2\n"},
+			lines: []int{3, 9},
+		},
+		{
+			description: "carriage returns",
+			data:        "a: 1\r\n---\r\nb: 2",
+			docs:        []string{"a: 1\n", "b: 2\n"},
+			lines:       []int{1, 3},
+		},
+		{
+			description: "repeated document",
+			data:        "a: 1\n---\na: 1\n",
+			docs:        []string{"a: 1\n", "a: 1\n"},
+			lines:       []int{1, 3},
+		},
+		{
+			description: "json stream",
+			data:        "{\"a\": 1}\n\n{\n  \"b\": 2\n}\n",
+			docs:        []string{`{"a": 1}`, "{\n  \"b\": 2\n}"},
+			lines:       []int{1, 3},
+		},
+		{
+			description: "content on the separator line",
+			data:        "a: 1\n--- {b: 2}\n",
+			err:         true,
+		},
+	}
+	for _, tc := range testcases {
+		docs, err := splitDocuments([]byte(tc.data))
+		assert.Equal(t, tc.err, err != nil, tc.description, err)
+		if tc.err {
+			continue
+		}
+		var data []string
+		var lines []int
+		for _, doc := range docs {
+			data = append(data, string(doc.data))
+			lines = append(lines, doc.line)
+		}
+		assert.Equal(t, tc.docs, data, tc.description)
+		assert.Equal(t, tc.lines, lines, tc.description)
+	}
+}
+
+func TestLoadManifests(t *testing.T) {
+	files := map[string]string{
+		"configmap.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: script
+  namespace: apps
+data:
+  script.sh: |
+    echo one
+    ---
+    echo two
+---
+this is not: [valid yaml
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: httpbin
+  namespace: apps
+`,
+		"list.json": `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {"apiVersion": "v1", "kind": "Service", "metadata": {"name": "httpbin", "namespace": "apps"}},
+    {"apiVersion": "v1", "kind": "Service", "metadata": {"name": "other", "namespace": "apps"}}
+  ]
+}
+`,
+		"values.yaml": `replicaCount: 2
+`,
+		"crd.yml": `apiVersion: cert-manager.io/v1
+kind: Certificate
+metadata:
+  name: cert
+  namespace: apps
+`,
+		"README.md": `---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: ignored
+`,
+	}
+
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resources := NewResources()
+	if err := resources.LoadFromDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 1, len(resources.ConfigMaps))
+	assert.Equal(t, "echo one\n---\necho two\n", resources.ConfigMaps[0].Data["script.sh"])
+	assert.Equal(t, 1, len(resources.ServiceAccounts))
+	assert.Equal(t, 2, len(resources.Services))
+	assert.Equal(t, 1, len(resources.Namespaces))
+	assert.Equal(t, "apps", resources.Namespaces[0].Name)
+
+	file := filepath.ToSlash(filepath.Join(dir, "configmap.yaml"))
+	assert.Equal(t, []Source{{File: file, Line: 14}}, resources.Sources("v1", "ServiceAccount", "apps:httpbin"))
+	assert.Equal(t, "list.json:1", filepath.Base(resources.Sources("v1", "Service", "apps:other")[0].String()))
+	assert.Equal(t, 0, len(resources.Sources("v1", "Namespace", "apps")))
+}
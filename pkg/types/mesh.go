@@ -0,0 +1,226 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/gogo/protobuf/jsonpb"
+	log "github.com/sirupsen/logrus"
+	meshv1alpha1 "istio.io/api/mesh/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// MeshConfigMapName is the name of the ConfigMap that holds the mesh
+	// configuration of the istio control plane.
+	MeshConfigMapName = "istio"
+	// MeshConfigKey is the key of the mesh configuration in the ConfigMap.
+	MeshConfigKey = "mesh"
+	// DefaultRootNamespace is the root namespace of a mesh that does not
+	// configure one.
+	DefaultRootNamespace = "istio-system"
+)
+
+// ParseMeshConfig parses a mesh configuration in YAML or JSON, as found in
+// the mesh key of the istio ConfigMap. Fields unknown to this version of the
+// API are ignored, so that configuration of newer control planes parses.
+func ParseMeshConfig(data []byte) (*meshv1alpha1.MeshConfig, error) {
+	js, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	mesh := &meshv1alpha1.MeshConfig{}
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	if err := unmarshaler.Unmarshal(bytes.NewReader(js), mesh); err != nil {
+		return nil, err
+	}
+	return mesh, nil
+}
+
+// EncodeMeshConfig encodes a mesh configuration as YAML.
+func EncodeMeshConfig(mesh *meshv1alpha1.MeshConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := (&jsonpb.Marshaler{}).Marshal(&buf, mesh); err != nil {
+		return nil, err
+	}
+	return yaml.JSONToYAML(buf.Bytes())
+}
+
+// LoadMeshConfigFile loads the mesh configuration from the named file, which
+// holds either the istio ConfigMap or the mesh configuration itself. It takes
+// precedence over a mesh configuration found in the loaded resources.
+func (r *Resources) LoadMeshConfigFile(name string) error {
+	data, err := os.ReadFile(name) // nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(data, &typeMeta); err == nil && typeMeta.Kind == "ConfigMap" {
+		var cm corev1.ConfigMap
+		if err := yaml.Unmarshal(data, &cm); err != nil {
+			return err
+		}
+		mesh, ok := cm.Data[MeshConfigKey]
+		if !ok {
+			return fmt.Errorf("%s: ConfigMap has no %s key", name, MeshConfigKey)
+		}
+		data = []byte(mesh)
+	}
+
+	mesh, err := ParseMeshConfig(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	r.MeshConfig = mesh
+	r.meshConfigMap = ""
+	r.meshConfigFile = true
+	return nil
+}
+
+// RevisionMeshConfig is the mesh configuration of a control plane revision.
+type RevisionMeshConfig struct {
+	// Revision names the revision, empty if the configuration stands for
+	// the whole mesh.
+	Revision string
+	// ConfigMap is the "namespace:name" of the ConfigMap that the
+	// configuration was parsed from, empty if it was served by istiod.
+	ConfigMap string
+	// MeshConfig is the mesh configuration of the revision.
+	MeshConfig *meshv1alpha1.MeshConfig
+}
+
+// SetRevisionMeshConfig records the mesh configuration served by the istiod of
+// a revision, unless one is already known for the revision.
+func (r *Resources) SetRevisionMeshConfig(revision string, mesh *meshv1alpha1.MeshConfig) {
+	r.setRevisionMeshConfig(RevisionMeshConfig{Revision: revision, MeshConfig: mesh})
+}
+
+func (r *Resources) setRevisionMeshConfig(config RevisionMeshConfig) {
+	if _, ok := r.revisionMeshConfigs[config.Revision]; ok {
+		return
+	}
+	if r.revisionMeshConfigs == nil {
+		r.revisionMeshConfigs = make(map[string]RevisionMeshConfig)
+	}
+	r.revisionMeshConfigs[config.Revision] = config
+}
+
+// RevisionMeshConfig returns the mesh configuration of a revision, or nil if
+// it is not known.
+func (r *Resources) RevisionMeshConfig(revision string) *meshv1alpha1.MeshConfig {
+	return r.revisionMeshConfigs[revision].MeshConfig
+}
+
+// MeshConfigs returns the mesh configuration of each revision ordered by
+// revision name. Without any configuration per revision, or once one was
+// read from a file, MeshConfig is returned on its own, for the whole mesh.
+func (r *Resources) MeshConfigs() []RevisionMeshConfig {
+	if r.meshConfigFile || len(r.revisionMeshConfigs) == 0 {
+		if r.MeshConfig == nil {
+			return nil
+		}
+		return []RevisionMeshConfig{{ConfigMap: r.meshConfigMap, MeshConfig: r.MeshConfig}}
+	}
+
+	var configs []RevisionMeshConfig
+	for _, config := range r.revisionMeshConfigs {
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Revision < configs[j].Revision
+	})
+	return configs
+}
+
+// LoadMeshConfigMap loads the mesh configuration of each revision from its
+// ConfigMap, the istio ConfigMap for the default revision and istio-<revision>
+// for the others, unless istiod served one for the revision. Only ConfigMaps
+// in the istio namespace are considered, the namespace defaulting to
+// istio-system, so that a ConfigMap named istio in an application namespace is
+// not mistaken for one. The configuration of the default revision, or else of
+// the first revision found, is also used as MeshConfig unless one was set.
+// Nothing is loaded once the mesh configuration was read from a file.
+func (r *Resources) LoadMeshConfigMap(disco Discovery) {
+	if r.meshConfigFile {
+		return
+	}
+	namespace := disco.IstioNamespace
+	if namespace == "" {
+		namespace = DefaultRootNamespace
+	}
+
+	// the default revision comes first, as the primary revision does
+	revisions := []string{DefaultRevision}
+	for _, rev := range Revisions(disco, *r) {
+		if rev != DefaultRevision {
+			revisions = append(revisions, rev)
+		}
+	}
+	for _, rev := range revisions {
+		name := MeshConfigMapName
+		if rev != DefaultRevision {
+			name += "-" + rev
+		}
+		for i := range r.ConfigMaps {
+			cm := &r.ConfigMaps[i]
+			if _, ok := cm.Data[MeshConfigKey]; !ok || cm.Namespace != namespace || cm.Name != name {
+				continue
+			}
+			mesh, err := ParseMeshConfig([]byte(cm.Data[MeshConfigKey]))
+			if err != nil {
+				log.WithFields(log.Fields{
+					"configmap": cm.Namespace + "/" + cm.Name,
+					"err":       err,
+				}).Warn("failed to parse mesh config")
+				continue
+			}
+
+			id := cm.Namespace + ":" + cm.Name
+			r.setRevisionMeshConfig(RevisionMeshConfig{Revision: rev, ConfigMap: id, MeshConfig: mesh})
+			if r.MeshConfig == nil {
+				r.MeshConfig = mesh
+				r.meshConfigMap = id
+			}
+		}
+	}
+}
+
+// MeshConfigMap returns the "namespace:name" of the ConfigMap that the mesh
+// configuration was loaded from, or an empty string if it was loaded from a
+// file or the control plane.
+func (r *Resources) MeshConfigMap() string {
+	return r.meshConfigMap
+}
+
+// RootNamespace returns the root namespace of the mesh, whose policies apply
+// to every namespace. It is read from the mesh configuration, falling back to
+// the istio namespace and then to istio-system.
+func RootNamespace(disco Discovery, resources Resources) string {
+	if ns := resources.MeshConfig.GetRootNamespace(); ns != "" {
+		return ns
+	}
+	if disco.IstioNamespace != "" {
+		return disco.IstioNamespace
+	}
+	return DefaultRootNamespace
+}
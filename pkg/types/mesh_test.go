@@ -0,0 +1,232 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	meshv1alpha1 "istio.io/api/mesh/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const meshConfigMapYAML = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: istio
+  namespace: istio-control
+data:
+  mesh: |-
+    rootNamespace: istio-config
+    trustDomain: example.com
+    enableAutoMtls: false
+    outboundTrafficPolicy:
+      mode: REGISTRY_ONLY
+    futureSetting: true
+`
+
+func TestLoadMeshConfigMap(t *testing.T) {
+	resources := NewResources()
+	err := resources.LoadFromReader("istio.yaml", strings.NewReader(meshConfigMapYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resources.LoadMeshConfigMap(Discovery{IstioNamespace: "istio-control"})
+
+	mesh := resources.MeshConfig
+	if mesh == nil {
+		t.Fatal("mesh config not loaded")
+	}
+	assert.Equal(t, "istio-config", mesh.GetRootNamespace())
+	assert.Equal(t, "example.com", mesh.GetTrustDomain())
+	assert.Equal(t, false, mesh.GetEnableAutoMtls().GetValue())
+	assert.Equal(t, meshv1alpha1.MeshConfig_OutboundTrafficPolicy_REGISTRY_ONLY, mesh.GetOutboundTrafficPolicy().GetMode())
+	assert.Equal(t, "istio-control:istio", resources.MeshConfigMap())
+}
+
+func TestLoadMeshConfigMapCandidates(t *testing.T) {
+	configMap := func(namespace, name, rootNamespace string) runtime.Object {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Data:       map[string]string{MeshConfigKey: "rootNamespace: " + rootNamespace},
+		}
+	}
+	canary := Discovery{
+		IstioNamespace: "istio-control",
+		Revisions:      []Revision{{Name: "canary"}},
+	}
+
+	type testcase struct {
+		description string
+		disco       Discovery
+		objects     []runtime.Object
+		configMap   string
+	}
+	testcases := []testcase{
+		{
+			description: "istio namespace",
+			disco:       Discovery{IstioNamespace: "istio-control"},
+			objects: []runtime.Object{
+				configMap("apps", "istio", "tenant"),
+				configMap("istio-control", "istio", "mesh"),
+			},
+			configMap: "istio-control:istio",
+		},
+		{
+			description: "default istio namespace",
+			objects: []runtime.Object{
+				configMap("apps", "istio", "tenant"),
+				configMap("istio-system", "istio", "mesh"),
+			},
+			configMap: "istio-system:istio",
+		},
+		{
+			description: "only in another namespace",
+			disco:       Discovery{IstioNamespace: "istio-control"},
+			objects:     []runtime.Object{configMap("apps", "istio", "tenant")},
+		},
+		{
+			description: "revision",
+			disco:       canary,
+			objects: []runtime.Object{
+				configMap("apps", "istio-canary", "tenant"),
+				configMap("istio-control", "istio-canary", "mesh"),
+			},
+			configMap: "istio-control:istio-canary",
+		},
+		{
+			description: "default revision first",
+			disco:       canary,
+			objects: []runtime.Object{
+				configMap("istio-control", "istio-canary", "canary"),
+				configMap("istio-control", "istio", "mesh"),
+			},
+			configMap: "istio-control:istio",
+		},
+		{
+			description: "undiscovered revision",
+			disco:       Discovery{IstioNamespace: "istio-control"},
+			objects:     []runtime.Object{configMap("istio-control", "istio-canary", "canary")},
+		},
+	}
+	for _, tc := range testcases {
+		resources := NewResources()
+		resources.Load(tc.objects)
+		resources.LoadMeshConfigMap(tc.disco)
+		assert.Equal(t, tc.configMap, resources.MeshConfigMap(), tc.description)
+		if tc.configMap != "" {
+			assert.Equal(t, "mesh", resources.MeshConfig.GetRootNamespace(), tc.description)
+		} else {
+			assert.Equal(t, (*meshv1alpha1.MeshConfig)(nil), resources.MeshConfig, tc.description)
+		}
+	}
+}
+
+func TestMeshConfigs(t *testing.T) {
+	resources := NewResources()
+	resources.Load([]runtime.Object{
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+			Data:       map[string]string{MeshConfigKey: "trustDomain: stable.example.com"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-canary", Namespace: "istio-system"},
+			Data:       map[string]string{MeshConfigKey: "trustDomain: cm.example.com"},
+		},
+	})
+	// istiod of the canary revision served its configuration first
+	resources.SetRevisionMeshConfig("canary", &meshv1alpha1.MeshConfig{TrustDomain: "served.example.com"})
+	resources.LoadMeshConfigMap(Discovery{Revisions: []Revision{{Name: "canary"}, {Name: DefaultRevision}}})
+
+	var revisions, configMaps, trustDomains []string
+	for _, config := range resources.MeshConfigs() {
+		revisions = append(revisions, config.Revision)
+		configMaps = append(configMaps, config.ConfigMap)
+		trustDomains = append(trustDomains, config.MeshConfig.GetTrustDomain())
+	}
+	assert.Equal(t, []string{"canary", DefaultRevision}, revisions)
+	assert.Equal(t, []string{"", "istio-system:istio"}, configMaps)
+	assert.Equal(t, []string{"served.example.com", "stable.example.com"}, trustDomains)
+	assert.Equal(t, "stable.example.com", resources.MeshConfig.GetTrustDomain())
+
+	// a file replaces the configuration of every revision
+	name := filepath.Join(t.TempDir(), "mesh.yaml")
+	if err := os.WriteFile(name, []byte("trustDomain: file.example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := resources.LoadMeshConfigFile(name); err != nil {
+		t.Fatal(err)
+	}
+	configs := resources.MeshConfigs()
+	assert.Equal(t, 1, len(configs))
+	assert.Equal(t, "", configs[0].Revision)
+	assert.Equal(t, "file.example.com", configs[0].MeshConfig.GetTrustDomain())
+}
+
+func TestLoadMeshConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	type testcase struct {
+		name     string
+		data     string
+		expected string
+	}
+
+	cases := []testcase{
+		{"configmap.yaml", meshConfigMapYAML, "istio-config"},
+		{"mesh.yaml", "rootNamespace: mesh-root\n", "mesh-root"},
+		{"mesh.json", `{"rootNamespace": "json-root"}`, "json-root"},
+	}
+
+	for _, c := range cases {
+		name := filepath.Join(dir, c.name)
+		if err := os.WriteFile(name, []byte(c.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		resources := NewResources()
+		if err := resources.LoadMeshConfigFile(name); err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		assert.Equal(t, c.expected, resources.MeshConfig.GetRootNamespace(), c.name)
+		assert.Equal(t, "", resources.MeshConfigMap(), c.name)
+	}
+}
+
+func TestRootNamespace(t *testing.T) {
+	type testcase struct {
+		disco    Discovery
+		mesh     *meshv1alpha1.MeshConfig
+		expected string
+	}
+
+	cases := []testcase{
+		{Discovery{}, nil, DefaultRootNamespace},
+		{Discovery{IstioNamespace: "istio-control"}, nil, "istio-control"},
+		{Discovery{IstioNamespace: "istio-control"}, &meshv1alpha1.MeshConfig{}, "istio-control"},
+		{Discovery{IstioNamespace: "istio-control"}, &meshv1alpha1.MeshConfig{RootNamespace: "istio-config"}, "istio-config"},
+	}
+
+	for i, c := range cases {
+		resources := NewResources()
+		resources.MeshConfig = c.mesh
+		assert.Equal(t, c.expected, RootNamespace(c.disco, resources), i)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sources of discovery fields that were not set by a discovery strategy.
+const (
+	// SourceConfiguration marks a field given by a flag, environment variable
+	// or the configuration file.
+	SourceConfiguration = "configuration"
+	// SourceSnapshot marks a field read from a snapshot bundle.
+	SourceSnapshot = "snapshot"
+	// SourceXDS marks a version reported by istiod's xds.
+	SourceXDS = "xds"
+	// SourceDebugz marks a version reported by istiod's debug API.
+	SourceDebugz = "debugz"
+	// SourceKubernetesAPI marks revisions named by the resources collected
+	// from the kubernetes api.
+	SourceKubernetesAPI = "kubernetes-api"
+)
+
+// Provenance records how the values of a Discovery were arrived at: what set
+// each field, and every strategy that was tried.
+type Provenance struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// Fields holds what last set each field of the Discovery.
+	Fields []FieldSource `json:"fields,omitempty"`
+	// Attempts holds every strategy that ran, in the order they finished.
+	Attempts []Attempt `json:"attempts,omitempty"`
+	// Collection records the collection of resources from the kubernetes
+	// api, if any.
+	Collection *Collection `json:"collection,omitempty"`
+}
+
+// Collection records which kubernetes api server resources were collected
+// from, and the kinds that could not be collected.
+type Collection struct {
+	// Host is the address of the api server.
+	Host string `json:"host"`
+	// Context is the kubeconfig context, empty for the current context or the
+	// in-cluster service account.
+	Context string `json:"context,omitempty"`
+	// Forbidden lists the kinds that the credentials may not list, whose
+	// issues are therefore not reported.
+	Forbidden []string `json:"forbidden,omitempty"`
+	// Missing lists the kinds that the api server does not serve.
+	Missing []string `json:"missing,omitempty"`
+}
+
+// FieldSource records what set a field of a Discovery.
+type FieldSource struct {
+	// Field is the JSON name of the field in Discovery.
+	Field string `json:"field"`
+	// Source is the strategy that set the field, as "runner/strategy", or
+	// one of the Source constants.
+	Source string    `json:"source"`
+	Time   time.Time `json:"time"`
+}
+
+// Attempt records a run of a discovery strategy.
+type Attempt struct {
+	Runner   string    `json:"runner"`
+	Strategy string    `json:"strategy"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// Error is why the strategy failed, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// StrategySource returns the source of the fields set by a strategy.
+func StrategySource(runner, strategy string) string {
+	return fmt.Sprintf("%s/%s", runner, strategy)
+}
+
+// SetField records that source set field, replacing what set it before.
+func (p *Provenance) SetField(field, source string, at time.Time) {
+	fs := FieldSource{Field: field, Source: source, Time: at}
+	for i := range p.Fields {
+		if p.Fields[i].Field == field {
+			p.Fields[i] = fs
+			return
+		}
+	}
+	p.Fields = append(p.Fields, fs)
+}
+
+// Field returns what set a field, or nil if it is not recorded.
+func (p *Provenance) Field(field string) *FieldSource {
+	for i := range p.Fields {
+		if p.Fields[i].Field == field {
+			return &p.Fields[i]
+		}
+	}
+	return nil
+}
+
+// Failed returns the attempts that failed.
+func (p *Provenance) Failed() []Attempt {
+	var failed []Attempt
+	for _, a := range p.Attempts {
+		if a.Error != "" {
+			failed = append(failed, a)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+)
+
+// RBAC describes what the service account token mounted in the workload
+// running snowcat is allowed to do.
+type RBAC struct {
+	// ServiceAccount is the "namespace:name" of the service account.
+	ServiceAccount string `json:"serviceAccount"`
+	// Rules are the rules that apply to the service account in its own
+	// namespace, as reported by a SelfSubjectRulesReview.
+	Rules []authorizationv1.ResourceRule `json:"rules,omitempty"`
+	// Incomplete is true if the rules are incomplete, which happens when an
+	// authorizer other than RBAC cannot enumerate its rules.
+	Incomplete bool `json:"incomplete,omitempty"`
+	// Permissions are the results of a SelfSubjectAccessReview of each
+	// sensitive verb and resource.
+	Permissions []Permission `json:"permissions,omitempty"`
+}
+
+// Permission is the result of an access review of a verb on a resource.
+type Permission struct {
+	Verb        string `json:"verb"`
+	Group       string `json:"group,omitempty"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	// Namespace is the namespace the review applies to. An empty namespace
+	// applies to all namespaces, or to a resource that is not namespaced.
+	Namespace string `json:"namespace,omitempty"`
+	Allowed   bool   `json:"allowed"`
+}
+
+// String returns the permission in the form "verb resource.group/subresource",
+// followed by the namespace it applies to.
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	if p.Namespace == "" {
+		return fmt.Sprintf("%s %s", p.Verb, resource)
+	}
+	return fmt.Sprintf("%s %s in %s", p.Verb, resource, p.Namespace)
+}
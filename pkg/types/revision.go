@@ -0,0 +1,183 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	// RevisionLabel labels the istiod of a control plane revision, and the
+	// namespaces and workloads that the revision serves.
+	RevisionLabel = "istio.io/rev"
+	// InjectionLabel enables sidecar injection by the default revision in a
+	// namespace.
+	InjectionLabel = "istio-injection"
+	// DefaultRevision names the control plane installed without a revision.
+	DefaultRevision = "default"
+)
+
+// Revision is one of the istio control planes that can run side by side in a
+// cluster, such as the old and new control planes of a canary upgrade.
+type Revision struct {
+	// Name is the istio.io/rev label of the revision, or DefaultRevision.
+	Name string `json:"name"`
+	// IstioVersion is the version of the revision's istiod.
+	IstioVersion string `json:"istioVersion,omitempty"`
+	// DiscoveryAddress is the IP:port of the revision's unauthenticated xds.
+	DiscoveryAddress string `json:"discoveryAddress,omitempty"`
+	// DebugzAddress is the IP:port of the revision's debug API.
+	DebugzAddress string `json:"debugzAddress,omitempty"`
+}
+
+// RevisionOf returns the revision named by the labels of an istiod workload,
+// or DefaultRevision if it has none.
+func RevisionOf(labels map[string]string) string {
+	if rev := labels[RevisionLabel]; rev != "" {
+		return rev
+	}
+	return DefaultRevision
+}
+
+// AddRevision records a revision, filling in the facts missing from a
+// revision of the same name that is already known.
+func (d *Discovery) AddRevision(rev Revision) {
+	existing := d.Revision(rev.Name)
+	if existing == nil {
+		d.Revisions = append(d.Revisions, rev)
+		return
+	}
+	if existing.IstioVersion == "" {
+		existing.IstioVersion = rev.IstioVersion
+	}
+	if existing.DiscoveryAddress == "" {
+		existing.DiscoveryAddress = rev.DiscoveryAddress
+	}
+	if existing.DebugzAddress == "" {
+		existing.DebugzAddress = rev.DebugzAddress
+	}
+}
+
+// Revision returns the named revision, or nil if it is not known.
+func (d *Discovery) Revision(name string) *Revision {
+	for i := range d.Revisions {
+		if d.Revisions[i].Name == name {
+			return &d.Revisions[i]
+		}
+	}
+	return nil
+}
+
+// PrimaryRevision returns the name of the default revision, or of the first
+// revision if there is no default one. Without revisions, the control plane
+// is the default revision.
+func (d *Discovery) PrimaryRevision() string {
+	if len(d.Revisions) == 0 || d.Revision(DefaultRevision) != nil {
+		return DefaultRevision
+	}
+	return d.Revisions[0].Name
+}
+
+// SetPrimaryRevision copies the facts of the default revision, or of the first
+// revision if there is no default one, to the top level of the discovery, so
+// that consumers unaware of revisions see a single control plane.
+func (d *Discovery) SetPrimaryRevision() {
+	if len(d.Revisions) == 0 {
+		return
+	}
+	primary := d.Revision(d.PrimaryRevision())
+	if primary.IstioVersion != "" {
+		d.IstioVersion = primary.IstioVersion
+	}
+	if primary.DiscoveryAddress != "" {
+		d.DiscoveryAddress = primary.DiscoveryAddress
+	}
+	if primary.DebugzAddress != "" {
+		d.DebugzAddress = primary.DebugzAddress
+	}
+}
+
+// Revisions returns the names of the control plane revisions that were
+// discovered or that have an istiod Deployment or Service among the resources.
+func Revisions(disco Discovery, resources Resources) []string {
+	names := make(map[string]struct{})
+	for _, rev := range disco.Revisions {
+		names[rev.Name] = struct{}{}
+	}
+	for _, name := range ResourceRevisions(resources) {
+		names[name] = struct{}{}
+	}
+
+	var revisions []string
+	for name := range names {
+		revisions = append(revisions, name)
+	}
+	sort.Strings(revisions)
+	return revisions
+}
+
+// ResourceRevisions returns the names of the control plane revisions that have
+// an istiod Deployment or Service among the resources, in order.
+func ResourceRevisions(resources Resources) []string {
+	names := make(map[string]struct{})
+	for _, deploy := range resources.Deployments {
+		if deploy.Labels["app"] == "istiod" {
+			names[RevisionOf(deploy.Labels)] = struct{}{}
+		}
+	}
+	for _, svc := range resources.Services {
+		if svc.Labels["app"] == "istiod" {
+			names[RevisionOf(svc.Labels)] = struct{}{}
+		}
+	}
+
+	var revisions []string
+	for name := range names {
+		revisions = append(revisions, name)
+	}
+	sort.Strings(revisions)
+	return revisions
+}
+
+// NamespaceRevision returns the revision that injects the workloads of a
+// namespace, from its istio.io/rev or istio-injection label, or "" if the
+// namespace is not labeled for injection.
+func (r *Resources) NamespaceRevision(namespace string) string {
+	for _, ns := range r.Namespaces {
+		if ns.Name != namespace {
+			continue
+		}
+		if rev := ns.Labels[RevisionLabel]; rev != "" {
+			return rev
+		}
+		if ns.Labels[InjectionLabel] == "enabled" {
+			return DefaultRevision
+		}
+	}
+	return ""
+}
+
+// Namespace returns the namespace of the resource affected by a result, or ""
+// if the resource is not namespaced.
+func (res AuditResult) Namespace() string {
+	if res.Kind == "Namespace" {
+		return res.Resource
+	}
+	if i := strings.Index(res.Resource, ":"); i >= 0 {
+		return res.Resource[:i]
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestAddRevision(t *testing.T) {
+	var disco Discovery
+	disco.AddRevision(Revision{Name: "1-14", DiscoveryAddress: "10.0.0.2:15010"})
+	disco.AddRevision(Revision{Name: DefaultRevision, DebugzAddress: "10.0.0.1:8080"})
+	disco.AddRevision(Revision{Name: "1-14", DiscoveryAddress: "10.0.0.3:15010", DebugzAddress: "10.0.0.2:8080"})
+
+	assert.Equal(t, 2, len(disco.Revisions))
+	assert.Equal(t, Revision{
+		Name:             "1-14",
+		DiscoveryAddress: "10.0.0.2:15010",
+		DebugzAddress:    "10.0.0.2:8080",
+	}, *disco.Revision("1-14"))
+
+	disco.SetPrimaryRevision()
+	assert.Equal(t, "", disco.DiscoveryAddress)
+	assert.Equal(t, "10.0.0.1:8080", disco.DebugzAddress)
+}
+
+func TestRevisions(t *testing.T) {
+	resources := NewResources()
+	resources.Load([]runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "legacy",
+			Labels: map[string]string{InjectionLabel: "enabled"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "canary",
+			Labels: map[string]string{RevisionLabel: "1-14"},
+		}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "plain"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name:      "istiod-1-14",
+			Namespace: "istio-system",
+			Labels:    map[string]string{"app": "istiod", RevisionLabel: "1-14"},
+		}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name:      "istiod-1-15",
+			Namespace: "istio-system",
+			Labels:    map[string]string{"app": "istiod", RevisionLabel: "1-15"},
+		}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Name:      "istio-ingressgateway",
+			Namespace: "istio-system",
+			Labels:    map[string]string{"app": "istio-ingressgateway", RevisionLabel: "1-16"},
+		}},
+	})
+
+	assert.Equal(t, []string{"1-14", "1-15"}, ResourceRevisions(resources))
+	disco := Discovery{Revisions: []Revision{{Name: DefaultRevision}}}
+	assert.Equal(t, []string{"1-14", "1-15", DefaultRevision}, Revisions(disco, resources))
+
+	assert.Equal(t, DefaultRevision, resources.NamespaceRevision("legacy"))
+	assert.Equal(t, "1-14", resources.NamespaceRevision("canary"))
+	assert.Equal(t, "", resources.NamespaceRevision("plain"))
+
+	res := AuditResult{Resource: "canary:reviews", Kind: "AuthorizationPolicy"}
+	assert.Equal(t, "canary", res.Namespace())
+}
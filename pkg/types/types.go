@@ -17,32 +17,100 @@ package types
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	log "github.com/sirupsen/logrus"
+	meshv1alpha1 "istio.io/api/mesh/v1alpha1"
+	extensionsv1alpha1 "istio.io/client-go/pkg/apis/extensions/v1alpha1"
 	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	networkingv1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
 	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	telemetryv1alpha1 "istio.io/client-go/pkg/apis/telemetry/v1alpha1"
 	istioscheme "istio.io/client-go/pkg/clientset/versioned/scheme"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+	gatewayv1alpha2 "sigs.k8s.io/gateway-api/apis/v1alpha2"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
 	"github.com/praetorian-inc/snowcat/pkg/util/namer"
 )
 
+// BestPracticesURL is the location of the Istio security best practices, which
+// auditors reference along with the anchor of the relevant section.
+const BestPracticesURL = "https://istio.io/latest/docs/ops/best-practices/security/"
+
 // AuditResult is a single instance of an issue discovered by an auditor.
 type AuditResult struct {
+	Auditor     string   `json:"auditor"`
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Severity    Severity `json:"severity"`
 	Resource    string   `json:"resource"`
+	// APIVersion and Kind identify the type of the affected resource.
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	// Path is the JSON path to the offending field within the resource,
+	// e.g. spec.rules[2].from[0].source.notNamespaces.
+	Path string `json:"path,omitempty"`
+	// Remediation describes how to resolve the issue.
+	Remediation string `json:"remediation,omitempty"`
+	// References are URLs with more information about the issue.
+	References []string `json:"references,omitempty"`
+	// ID distinguishes issues of an auditor that affect the same resource,
+	// such as the CVE of a known vulnerability. Auditors that report an
+	// element of a list set it to the content of that element, e.g. the
+	// host or listener name, since its index in Path shifts when the list
+	// changes.
+	ID string `json:"id,omitempty"`
+	// Fingerprint identifies the issue across runs, see Fingerprint.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Source is the file and line, as "path:line", where the affected
+	// resource is defined when it was loaded from a manifest.
+	Source string `json:"source,omitempty"`
+	// Cluster is the name of the cluster of the affected resource when
+	// several clusters are scanned together.
+	Cluster string `json:"cluster,omitempty"`
+	// Revision is the control plane revision that serves the affected
+	// resource when several revisions run side by side.
+	Revision string `json:"revision,omitempty"`
+}
+
+// Fingerprint returns a stable identifier for a result. It is derived from the
+// auditor, the cluster, kind and name of the affected resource, and the ID of
+// the result or, without an ID, its path, but not from its description,
+// references or API version, so rewording a finding, linking other
+// documentation or upgrading a manifest does not change it. Results without a
+// cluster have the same fingerprint as before clusters were named.
+func Fingerprint(res AuditResult) string {
+	h := sha256.New()
+	if res.Cluster != "" {
+		fmt.Fprintf(h, "cluster=%s\x00", res.Cluster)
+	}
+	path := res.Path
+	if res.ID != "" {
+		// the path only locates the issue, the ID names it
+		path = ""
+	}
+	for _, field := range []string{res.Auditor, res.Kind, res.Resource, path, res.ID} {
+		fmt.Fprintf(h, "%s\x00", field)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
 }
 
 // Severity represents the CVSS severity of an issue.
@@ -63,13 +131,67 @@ const (
 	Critical = iota
 )
 
+var severityNames = map[Severity]string{
+	Unknown:  "unknown",
+	None:     "none",
+	Low:      "low",
+	Medium:   "medium",
+	High:     "high",
+	Critical: "critical",
+}
+
+// String returns the lowercase name of the severity.
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity returns the Severity matching the case-insensitive name
+// (e.g. "high"), or an error if the name is not a known severity.
+func ParseSeverity(name string) (Severity, error) {
+	for severity, n := range severityNames {
+		if strings.EqualFold(n, name) {
+			return severity, nil
+		}
+	}
+	return Unknown, fmt.Errorf("unknown severity %q", name)
+}
+
+// SeverityFromScore returns the Severity of a CVSS base score.
+func SeverityFromScore(score float64) Severity {
+	switch {
+	case score >= 9.0:
+		return Critical
+	case score >= 7.0:
+		return High
+	case score >= 4.0:
+		return Medium
+	case score > 0:
+		return Low
+	default:
+		return None
+	}
+}
+
 // Auditor is the interface that all auditors conform to and is
 // required for auditor registration. Auditors should be scoped
-// to a single issue.
+// to a single issue, and implement either BasicAuditor or, when
+// they loop over many resources, ContextAuditor.
 type Auditor interface {
+	// ID returns a stable short identifier for the auditor in the form
+	// "<category>-<check>" (e.g. "authz-allow-negative"), where the category
+	// is the resource or area that the auditor inspects.
+	ID() string
 	// Name returns a human-readable name to be associated with the
 	// AuditResults from an auditor
 	Name() string
+}
+
+// BasicAuditor is an auditor that runs to completion without cancellation.
+type BasicAuditor interface {
+	Auditor
 	// Audit returns an array of AuditResults after scanning the
 	// provided Discovery and Resources for a particular issue.
 	// Audit may also return an error if required data is not
@@ -77,59 +199,190 @@ type Auditor interface {
 	Audit(Discovery, Resources) ([]AuditResult, error)
 }
 
+// ContextAuditor is an auditor that supports cancellation. It should return
+// ctx.Err() promptly once the context is done.
+type ContextAuditor interface {
+	Auditor
+	// AuditContext behaves like Audit, but stops early if ctx is canceled
+	// or its deadline is exceeded.
+	AuditContext(ctx context.Context, disco Discovery, resources Resources) ([]AuditResult, error)
+}
+
+// Scoper is an optional interface for auditors that can report which
+// resources they evaluate, so reports can list passing checks alongside
+// failures.
+type Scoper interface {
+	// Scope returns the resources that Audit evaluates for the provided
+	// Discovery and Resources, in the same form as AuditResult.Resource.
+	Scope(Discovery, Resources) []string
+}
+
+// ClusterAuditor is an optional interface for auditors that compare several
+// clusters sharing a mesh, such as the peers of a multi-primary mesh. They
+// are still run against each cluster on its own.
+type ClusterAuditor interface {
+	Auditor
+	// AuditClusters returns the issues found by comparing the clusters. It
+	// is only called when more than one cluster is scanned, and each result
+	// names the cluster it affects in its Cluster field.
+	AuditClusters(clusters []Cluster) ([]AuditResult, error)
+}
+
+// Cluster is the Discovery and Resources of one of several scanned clusters.
+type Cluster struct {
+	// Name identifies the cluster in results, such as the name of its
+	// kubeconfig context or input. It is empty when a single cluster is
+	// scanned.
+	Name      string
+	Discovery Discovery
+	Resources Resources
+}
+
+// FindCluster returns the cluster with the name, or nil if there is none.
+func FindCluster(clusters []Cluster, name string) *Cluster {
+	for i := range clusters {
+		if clusters[i].Name == name {
+			return &clusters[i]
+		}
+	}
+	return nil
+}
+
 // Discovery represents all facts learned during the discovery phase of the scanner.
 // These facts are used to populate the Resources from a deployment and are passed
 // to each auditor to help with its scanning.
 type Discovery struct {
-	// IstioVersion is the version of the istio control plane.
-	IstioVersion string
+	// IstioVersion is the version of the istio control plane. When several
+	// revisions run, it is that of the default revision.
+	IstioVersion string `json:"istioVersion,omitempty"`
 	// IstioNamespace is the Kubernetes namespace of the istio control plane.
-	IstioNamespace string
+	IstioNamespace string `json:"istioNamespace,omitempty"`
 	// DiscoveryAddress is the IP:port of istiod's unauthenticated xds.
-	DiscoveryAddress string
+	DiscoveryAddress string `json:"discoveryAddress,omitempty"`
 	// DebugzAddress is the IP:port of istiod's debug API.
-	DebugzAddress string
+	DebugzAddress string `json:"debugzAddress,omitempty"`
+	// Revisions are the control plane revisions that were discovered, each
+	// with its own istiod.
+	Revisions []Revision `json:"revisions,omitempty"`
 	// KubeletAddresses is a list of addresses of each node's kubelet read-only API.
 	// These addresses have the form "host:port".
-	KubeletAddresses []string
+	KubeletAddresses []string `json:"kubeletAddresses,omitempty"`
+	// RBAC holds the permissions of the service account that snowcat runs
+	// as, or nil if they were not probed.
+	RBAC *RBAC `json:"rbac,omitempty"`
+	// Provenance records how the other fields were discovered, or nil if
+	// nothing was recorded.
+	Provenance *Provenance `json:"provenance,omitempty"`
 }
 
 // Resources holds all known API objects related to the target. Resources are
 // populated by various clients (e.g. xds, kubelet) and contains several
 // different types of object (e.g. Namespaces, Pods, AuthorizationPolicies).
+// Secrets are only kept as metadata and type, their data is never retained.
+// Kubernetes Gateway API objects that are served at both v1alpha2 and v1beta1
+// are kept as v1beta1, and KubernetesGateways holds the Gateway API Gateways
+// as opposed to the Istio Gateways.
 type Resources struct {
 	counter int
 	decoder runtime.Decoder
 	seen    map[string]struct{}
 
-	Namespaces            []corev1.Namespace
-	Pods                  []corev1.Pod
-	PeerAuthentications   []securityv1beta1.PeerAuthentication
-	AuthorizationPolicies []securityv1beta1.AuthorizationPolicy
-	DestinationRules      []networkingv1alpha3.DestinationRule
-	Gateways              []networkingv1alpha3.Gateway
-	VirtualServices       []networkingv1alpha3.VirtualService
-	EnvoyFilters          []networkingv1alpha3.EnvoyFilter
-	ServiceEntries        []networkingv1alpha3.ServiceEntry
+	// source is the location of the object currently being loaded, if any,
+	// and sources maps the key of each object to the locations it was
+	// loaded from.
+	source  Source
+	sources map[string][]Source
+	// objects indexes every loaded object by group, kind and "namespace:name".
+	objects map[string]runtime.Object
+	// synthesized holds the names of namespaces that were created from the
+	// namespace of another object rather than loaded.
+	synthesized map[string]struct{}
+	// meshConfigMap is the "namespace:name" of the ConfigMap that
+	// MeshConfig was parsed from.
+	meshConfigMap string
+	// meshConfigFile is set once MeshConfig was read from a file, which
+	// replaces the configuration of every revision.
+	meshConfigFile bool
+	// revisionMeshConfigs holds the mesh configuration of each control
+	// plane revision by name.
+	revisionMeshConfigs map[string]RevisionMeshConfig
+
+	// MeshConfig is the mesh configuration of the control plane, that of
+	// the primary revision when several run, or nil if it was not found.
+	MeshConfig *meshv1alpha1.MeshConfig
+
+	Namespaces             []corev1.Namespace
+	Pods                   []corev1.Pod
+	Services               []corev1.Service
+	Endpoints              []corev1.Endpoints
+	EndpointSlices         []discoveryv1.EndpointSlice
+	ServiceAccounts        []corev1.ServiceAccount
+	ConfigMaps             []corev1.ConfigMap
+	Secrets                []corev1.Secret
+	Deployments            []appsv1.Deployment
+	ReplicaSets            []appsv1.ReplicaSet
+	StatefulSets           []appsv1.StatefulSet
+	DaemonSets             []appsv1.DaemonSet
+	NetworkPolicies        []networkingv1.NetworkPolicy
+	PeerAuthentications    []securityv1beta1.PeerAuthentication
+	AuthorizationPolicies  []securityv1beta1.AuthorizationPolicy
+	RequestAuthentications []securityv1beta1.RequestAuthentication
+	DestinationRules       []networkingv1alpha3.DestinationRule
+	Gateways               []networkingv1alpha3.Gateway
+	VirtualServices        []networkingv1alpha3.VirtualService
+	EnvoyFilters           []networkingv1alpha3.EnvoyFilter
+	ServiceEntries         []networkingv1alpha3.ServiceEntry
+	Sidecars               []networkingv1alpha3.Sidecar
+	WorkloadEntries        []networkingv1alpha3.WorkloadEntry
+	WorkloadGroups         []networkingv1alpha3.WorkloadGroup
+	ProxyConfigs           []networkingv1beta1.ProxyConfig
+	Telemetries            []telemetryv1alpha1.Telemetry
+	WasmPlugins            []extensionsv1alpha1.WasmPlugin
+	GatewayClasses         []gatewayv1beta1.GatewayClass
+	KubernetesGateways     []gatewayv1beta1.Gateway
+	HTTPRoutes             []gatewayv1beta1.HTTPRoute
+	ReferenceGrants        []gatewayv1beta1.ReferenceGrant
+	TCPRoutes              []gatewayv1alpha2.TCPRoute
+	TLSRoutes              []gatewayv1alpha2.TLSRoute
+	GRPCRoutes             []gatewayv1alpha2.GRPCRoute
 }
 
 func init() {
-	err := istioscheme.AddToScheme(clientsetscheme.Scheme)
-	if err != nil {
-		panic(err)
+	schemes := []func(*runtime.Scheme) error{
+		istioscheme.AddToScheme,
+		gatewayv1alpha2.AddToScheme,
+		gatewayv1beta1.AddToScheme,
+	}
+	for _, addToScheme := range schemes {
+		if err := addToScheme(clientsetscheme.Scheme); err != nil {
+			panic(err)
+		}
+	}
+	for _, served := range servedVersions {
+		clientsetscheme.Scheme.AddKnownTypes(served.gv, served.types...)
 	}
 }
 
 // NewResources returns Resources that can track and decode objects from clients.
 func NewResources() Resources {
 	return Resources{
-		decoder: clientsetscheme.Codecs.UniversalDeserializer(),
-		seen:    make(map[string]struct{}),
+		decoder:     clientsetscheme.Codecs.UniversalDeserializer(),
+		seen:        make(map[string]struct{}),
+		sources:     make(map[string][]Source),
+		objects:     make(map[string]runtime.Object),
+		synthesized: make(map[string]struct{}),
 	}
 }
 
 func (r *Resources) addIfNotExists(obj runtime.Object, meta metav1.ObjectMeta, add func()) {
 	gk := obj.GetObjectKind().GroupVersionKind().GroupKind()
+	if gk.Kind == "" {
+		// objects such as list items and synthesized namespaces carry no
+		// type information, so look it up from the scheme instead
+		if gvks, _, err := clientsetscheme.Scheme.ObjectKinds(obj); err == nil && len(gvks) > 0 {
+			gk = gvks[0].GroupKind()
+		}
+	}
 
 	var key string
 	if meta.Namespace == "" {
@@ -142,15 +395,61 @@ func (r *Resources) addIfNotExists(obj runtime.Object, meta metav1.ObjectMeta, a
 		return
 	}
 
-	// NOTE: this creates namespaces as we observe them, but without any labels or annotations
+	// NOTE: this creates namespaces as we observe them, but without any labels
+	// or annotations. they are replaced if the namespace itself is loaded later
 	if meta.Namespace != "" {
 		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: meta.Namespace}}
-		r.addIfNotExists(ns, ns.ObjectMeta, func() { r.Namespaces = append(r.Namespaces, *ns) })
+		source := r.source
+		r.source = Source{}
+		r.addIfNotExists(ns, ns.ObjectMeta, func() {
+			r.Namespaces = append(r.Namespaces, *ns)
+			r.synthesized[ns.Name] = struct{}{}
+		})
+		r.source = source
 	}
 
 	add()
 	r.seen[key] = struct{}{}
 	r.counter++
+	objKey := objectKey(gk, meta.Namespace+":"+meta.Name)
+	r.objects[objKey] = obj
+	if r.source.File != "" {
+		r.sources[objKey] = append(r.sources[objKey], r.source)
+	}
+}
+
+// replaceNamespace replaces a synthesized namespace with the loaded one, so
+// that its labels and annotations are known.
+func (r *Resources) replaceNamespace(ns *corev1.Namespace) {
+	for i := range r.Namespaces {
+		if r.Namespaces[i].Name == ns.Name {
+			r.Namespaces[i] = *ns
+		}
+	}
+	delete(r.synthesized, ns.Name)
+	key := objectKey(schema.GroupKind{Kind: "Namespace"}, ":"+ns.Name)
+	r.objects[key] = ns
+	if r.source.File != "" {
+		r.sources[key] = append(r.sources[key], r.source)
+	}
+}
+
+// gatewayBeta clears the type information of a Gateway API object converted
+// from v1alpha2, so that it is resolved as v1beta1 from the scheme.
+func gatewayBeta(obj runtime.Object) runtime.Object {
+	obj.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+	return obj
+}
+
+// secretMetadata returns a copy of a Secret without its data. The last
+// applied configuration annotation is dropped as well, since it may contain
+// the data too.
+func secretMetadata(secret *corev1.Secret) *corev1.Secret {
+	res := secret.DeepCopy()
+	res.Data = nil
+	res.StringData = nil
+	delete(res.Annotations, corev1.LastAppliedConfigAnnotation)
+	return res
 }
 
 // Load processes an array of Kubernetes runtime objects and adds relevant
@@ -201,14 +500,141 @@ func (r *Resources) Load(resources []runtime.Object) {
 			r.addIfNotExists(resource, obj.ObjectMeta, func() {
 				r.ServiceEntries = append(r.ServiceEntries, *obj)
 			})
+		case *securityv1beta1.RequestAuthentication:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.RequestAuthentications = append(r.RequestAuthentications, *obj)
+			})
+		case *networkingv1alpha3.Sidecar:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.Sidecars = append(r.Sidecars, *obj)
+			})
+		case *networkingv1alpha3.WorkloadEntry:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.WorkloadEntries = append(r.WorkloadEntries, *obj)
+			})
+		case *networkingv1alpha3.WorkloadGroup:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.WorkloadGroups = append(r.WorkloadGroups, *obj)
+			})
+		case *networkingv1beta1.ProxyConfig:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.ProxyConfigs = append(r.ProxyConfigs, *obj)
+			})
+		case *networkingv1beta1.DestinationRule:
+			r.loadConverted(obj, &networkingv1alpha3.DestinationRule{})
+		case *networkingv1beta1.Gateway:
+			r.loadConverted(obj, &networkingv1alpha3.Gateway{})
+		case *networkingv1beta1.VirtualService:
+			r.loadConverted(obj, &networkingv1alpha3.VirtualService{})
+		case *networkingv1beta1.ServiceEntry:
+			r.loadConverted(obj, &networkingv1alpha3.ServiceEntry{})
+		case *networkingv1beta1.Sidecar:
+			r.loadConverted(obj, &networkingv1alpha3.Sidecar{})
+		case *networkingv1beta1.WorkloadEntry:
+			r.loadConverted(obj, &networkingv1alpha3.WorkloadEntry{})
+		case *networkingv1beta1.WorkloadGroup:
+			r.loadConverted(obj, &networkingv1alpha3.WorkloadGroup{})
+		case *telemetryv1alpha1.Telemetry:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.Telemetries = append(r.Telemetries, *obj)
+			})
+		case *extensionsv1alpha1.WasmPlugin:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.WasmPlugins = append(r.WasmPlugins, *obj)
+			})
+		case *gatewayv1beta1.GatewayClass:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.GatewayClasses = append(r.GatewayClasses, *obj)
+			})
+		case *gatewayv1alpha2.GatewayClass:
+			r.Load([]runtime.Object{gatewayBeta((*gatewayv1beta1.GatewayClass)(obj))})
+		case *gatewayv1beta1.Gateway:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.KubernetesGateways = append(r.KubernetesGateways, *obj)
+			})
+		case *gatewayv1alpha2.Gateway:
+			r.Load([]runtime.Object{gatewayBeta((*gatewayv1beta1.Gateway)(obj))})
+		case *gatewayv1beta1.HTTPRoute:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.HTTPRoutes = append(r.HTTPRoutes, *obj)
+			})
+		case *gatewayv1alpha2.HTTPRoute:
+			r.Load([]runtime.Object{gatewayBeta((*gatewayv1beta1.HTTPRoute)(obj))})
+		case *gatewayv1beta1.ReferenceGrant:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.ReferenceGrants = append(r.ReferenceGrants, *obj)
+			})
+		case *gatewayv1alpha2.ReferenceGrant:
+			r.Load([]runtime.Object{gatewayBeta((*gatewayv1beta1.ReferenceGrant)(obj))})
+		case *gatewayv1alpha2.TCPRoute:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.TCPRoutes = append(r.TCPRoutes, *obj)
+			})
+		case *gatewayv1alpha2.TLSRoute:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.TLSRoutes = append(r.TLSRoutes, *obj)
+			})
+		case *gatewayv1alpha2.GRPCRoute:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.GRPCRoutes = append(r.GRPCRoutes, *obj)
+			})
 		case *corev1.Pod:
 			r.addIfNotExists(resource, obj.ObjectMeta, func() {
 				r.Pods = append(r.Pods, *obj)
 			})
 		case *corev1.Namespace:
+			if _, ok := r.synthesized[obj.Name]; ok {
+				r.replaceNamespace(obj)
+				continue
+			}
 			r.addIfNotExists(resource, obj.ObjectMeta, func() {
 				r.Namespaces = append(r.Namespaces, *obj)
 			})
+		case *corev1.Service:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.Services = append(r.Services, *obj)
+			})
+		case *corev1.Endpoints:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.Endpoints = append(r.Endpoints, *obj)
+			})
+		case *discoveryv1.EndpointSlice:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.EndpointSlices = append(r.EndpointSlices, *obj)
+			})
+		case *corev1.ServiceAccount:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.ServiceAccounts = append(r.ServiceAccounts, *obj)
+			})
+		case *corev1.ConfigMap:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.ConfigMaps = append(r.ConfigMaps, *obj)
+			})
+		case *corev1.Secret:
+			secret := secretMetadata(obj)
+			r.addIfNotExists(secret, secret.ObjectMeta, func() {
+				r.Secrets = append(r.Secrets, *secret)
+			})
+		case *appsv1.Deployment:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.Deployments = append(r.Deployments, *obj)
+			})
+		case *appsv1.ReplicaSet:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.ReplicaSets = append(r.ReplicaSets, *obj)
+			})
+		case *appsv1.StatefulSet:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.StatefulSets = append(r.StatefulSets, *obj)
+			})
+		case *appsv1.DaemonSet:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.DaemonSets = append(r.DaemonSets, *obj)
+			})
+		case *networkingv1.NetworkPolicy:
+			r.addIfNotExists(resource, obj.ObjectMeta, func() {
+				r.NetworkPolicies = append(r.NetworkPolicies, *obj)
+			})
 		case *runtime.Unknown:
 			unknown, _, err := r.decoder.Decode(obj.Raw, nil, nil)
 			if err != nil {
@@ -228,41 +654,69 @@ func (r *Resources) Load(resources []runtime.Object) {
 	}
 }
 
-// LoadFromDirectory processes all YAML files within a directory, decodes them
-// as Kubernetes resources, and loads them into the state.
+// LoadFromDirectory processes all YAML and JSON files within a directory,
+// decodes them as Kubernetes resources, and loads them into the state. Files
+// with other extensions and documents that are not Kubernetes resources are
+// skipped, and documents that fail to decode are logged with their location.
 func (r *Resources) LoadFromDirectory(dir string) error {
-	root := os.DirFS(dir)
+	return r.LoadFromFS(os.DirFS(dir), filepath.ToSlash(dir))
+}
 
-	return fs.WalkDir(root, ".", func(path string, d fs.DirEntry, err error) error {
+// LoadFromFS processes all YAML and JSON files within a file system as
+// LoadFromDirectory does. The sources of loaded resources are recorded
+// relative to root.
+func (r *Resources) LoadFromFS(fsys fs.FS, root string) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 		if d.IsDir() {
 			return nil
 		}
-		data, err := fs.ReadFile(root, path)
+		if !isManifest(name) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
 		if err != nil {
 			return err
 		}
-		return r.load(data)
+
+		r.load(path.Join(root, name), data)
+		return nil
 	})
 }
 
-func (r *Resources) load(data []byte) error {
-	var resources []runtime.Object
-	for _, yaml := range bytes.Split(data, []byte("---")) {
-		if len(yaml) == 0 {
-			continue
-		}
-		obj, _, err := r.decoder.Decode(yaml, nil, nil)
-		if err != nil {
-			return err
-		}
+// Sources returns the locations that the object of the given API version, kind
+// and resource, in the "namespace:name" form used by AuditResult, was loaded
+// from. Objects collected from a live cluster have no sources.
+func (r *Resources) Sources(apiVersion, kind, resource string) []Source {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil
+	}
+	if !strings.Contains(resource, ":") {
+		resource = ":" + resource
+	}
+	return r.sources[objectKey(gv.WithKind(kind).GroupKind(), resource)]
+}
+
+func objectKey(gk schema.GroupKind, resource string) string {
+	return gk.String() + "/" + resource
+}
 
-		resources = append(resources, obj)
+// Lookup returns the loaded object of the given API version, kind and
+// resource, in the "namespace:name" form used by AuditResult. Only the group
+// of the API version is significant. Cluster-scoped resources may be given by
+// name alone. Lookup returns nil if no such object was loaded.
+func (r *Resources) Lookup(apiVersion, kind, resource string) runtime.Object {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil
 	}
-	r.Load(resources)
-	return nil
+	if !strings.Contains(resource, ":") {
+		resource = ":" + resource
+	}
+	return r.objects[objectKey(gv.WithKind(kind).GroupKind(), resource)]
 }
 
 // Len returns the number of resources within the state.
@@ -276,57 +730,111 @@ func (r *Resources) Export(dir string) error {
 		return err
 	}
 
+	return r.ExportTo(func(name string, data []byte) error {
+		return os.WriteFile(filepath.Join(dir, name), data, 0600)
+	})
+}
+
+// ExportFunc receives the file name and YAML encoding of a list of resources.
+type ExportFunc func(name string, data []byte) error
+
+// ExportTo encodes all known resources as YAML lists, one per kind, and passes
+// each to export under the file name that Export would write it to.
+func (r *Resources) ExportTo(export ExportFunc) error {
 	var errs error
 	lists := []runtime.Object{
 		&corev1.NamespaceList{Items: r.Namespaces},
 		&corev1.PodList{Items: r.Pods},
+		&corev1.ServiceList{Items: r.Services},
+		&corev1.EndpointsList{Items: r.Endpoints},
+		&discoveryv1.EndpointSliceList{Items: r.EndpointSlices},
+		&corev1.ServiceAccountList{Items: r.ServiceAccounts},
+		&corev1.ConfigMapList{Items: r.ConfigMaps},
+		&corev1.SecretList{Items: r.Secrets},
+		&appsv1.DeploymentList{Items: r.Deployments},
+		&appsv1.ReplicaSetList{Items: r.ReplicaSets},
+		&appsv1.StatefulSetList{Items: r.StatefulSets},
+		&appsv1.DaemonSetList{Items: r.DaemonSets},
+		&networkingv1.NetworkPolicyList{Items: r.NetworkPolicies},
 		&networkingv1alpha3.DestinationRuleList{Items: r.DestinationRules},
 		&networkingv1alpha3.EnvoyFilterList{Items: r.EnvoyFilters},
 		&networkingv1alpha3.GatewayList{Items: r.Gateways},
 		&networkingv1alpha3.ServiceEntryList{Items: r.ServiceEntries},
+		&networkingv1alpha3.SidecarList{Items: r.Sidecars},
 		&networkingv1alpha3.VirtualServiceList{Items: r.VirtualServices},
+		&networkingv1alpha3.WorkloadEntryList{Items: r.WorkloadEntries},
+		&networkingv1alpha3.WorkloadGroupList{Items: r.WorkloadGroups},
+		&networkingv1beta1.ProxyConfigList{Items: r.ProxyConfigs},
 		&securityv1beta1.AuthorizationPolicyList{Items: r.AuthorizationPolicies},
 		&securityv1beta1.PeerAuthenticationList{Items: r.PeerAuthentications},
+		&securityv1beta1.RequestAuthenticationList{Items: r.RequestAuthentications},
+		&telemetryv1alpha1.TelemetryList{Items: r.Telemetries},
+		&extensionsv1alpha1.WasmPluginList{Items: r.WasmPlugins},
+		&gatewayv1beta1.GatewayClassList{Items: r.GatewayClasses},
+		&gatewayv1beta1.GatewayList{Items: r.KubernetesGateways},
+		&gatewayv1beta1.HTTPRouteList{Items: r.HTTPRoutes},
+		&gatewayv1beta1.ReferenceGrantList{Items: r.ReferenceGrants},
+		&gatewayv1alpha2.TCPRouteList{Items: r.TCPRoutes},
+		&gatewayv1alpha2.TLSRouteList{Items: r.TLSRoutes},
+		&gatewayv1alpha2.GRPCRouteList{Items: r.GRPCRoutes},
 	}
 	for _, list := range lists {
-		err := exportObjects(dir, list)
+		err := exportObjects(list, export)
 		if err != nil {
-			errs = multierror.Append(err)
+			errs = multierror.Append(errs, err)
 		}
 	}
 	return errs
 }
 
-func exportObjects(dir string, obj runtime.Object) (err error) {
+// yamlEncoder returns an encoder that writes obj as YAML, along with its kind.
+func yamlEncoder(obj runtime.Object) (runtime.Encoder, schema.GroupVersionKind, error) {
 	const mediaType = runtime.ContentTypeYAML
 	info, ok := runtime.SerializerInfoForMediaType(clientsetscheme.Codecs.SupportedMediaTypes(), mediaType)
 	if !ok {
-		return fmt.Errorf("unable to locate encoder -- %q is not a supported media type", mediaType)
+		return nil, schema.GroupVersionKind{}, fmt.Errorf("unable to locate encoder -- %q is not a supported media type", mediaType)
 	}
 
 	gvks, _, err := clientsetscheme.Scheme.ObjectKinds(obj)
 	if err != nil {
-		return err
+		return nil, schema.GroupVersionKind{}, err
 	}
 	if len(gvks) == 0 {
-		return fmt.Errorf("unknown gvk for %T", obj)
+		return nil, schema.GroupVersionKind{}, fmt.Errorf("unknown gvk for %T", obj)
+	}
+
+	return clientsetscheme.Codecs.EncoderForVersion(info.Serializer, gvks[0].GroupVersion()), gvks[0], nil
+}
+
+// EncodeYAML returns the YAML representation of a known object.
+func EncodeYAML(obj runtime.Object) ([]byte, error) {
+	encoder, _, err := yamlEncoder(obj)
+	if err != nil {
+		return nil, err
 	}
 
-	encoder := clientsetscheme.Codecs.EncoderForVersion(info.Serializer, gvks[0].GroupVersion())
+	var buf bytes.Buffer
+	if err := encoder.Encode(obj, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
 
-	name := strings.TrimSuffix(gvks[0].Kind, "List")
-	filename := filepath.Join(dir, namer.PluralName(name)+".yaml")
-	f, err := os.Create(filename)
+func exportObjects(obj runtime.Object, export ExportFunc) error {
+	encoder, gvk, err := yamlEncoder(obj)
 	if err != nil {
 		return err
 	}
 
-	defer func() {
-		ferr := f.Close()
-		if ferr != nil && err == nil {
-			err = ferr
-		}
-	}()
+	name := namer.PluralName(strings.TrimSuffix(gvk.Kind, "List"))
+	if gvk.Group == gatewayv1beta1.GroupName {
+		// Gateway API kinds such as Gateway clash with the Istio kinds
+		name = "gatewayapi-" + name
+	}
 
-	return encoder.Encode(obj, f)
+	var buf bytes.Buffer
+	if err := encoder.Encode(obj, &buf); err != nil {
+		return err
+	}
+	return export(name+".yaml", buf.Bytes())
 }
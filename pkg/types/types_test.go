@@ -0,0 +1,367 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestParseSeverity(t *testing.T) {
+	for _, severity := range []Severity{Unknown, None, Low, Medium, High, Critical} {
+		parsed, err := ParseSeverity(severity.String())
+		assert.Equal(t, nil, err)
+		assert.Equal(t, severity, parsed)
+	}
+
+	parsed, err := ParseSeverity("HIGH")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Severity(High), parsed)
+
+	_, err = ParseSeverity("severe")
+	assert.NotEqual(t, nil, err)
+}
+
+func TestSeverityFromScore(t *testing.T) {
+	type testcase struct {
+		score    float64
+		expected Severity
+	}
+
+	testcases := []testcase{
+		{score: 0, expected: None},
+		{score: 3.9, expected: Low},
+		{score: 4.0, expected: Medium},
+		{score: 8.6, expected: High},
+		{score: 10, expected: Critical},
+	}
+	for i, tc := range testcases {
+		if v := SeverityFromScore(tc.score); v != tc.expected {
+			t.Errorf("[%d] got %s, expected %s", i, v, tc.expected)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	base := AuditResult{
+		Auditor:     "known-vulns",
+		Resource:    "Version 1.14.1",
+		ID:          "ISTIO-SECURITY-2022-005",
+		Description: "Vulnerable to ISTIO-SECURITY-2022-005",
+		References:  []string{"https://istio.io/latest/news/security/istio-security-2022-005/"},
+	}
+
+	type testcase struct {
+		description string
+		change      func(res *AuditResult)
+		same        bool
+	}
+	testcases := []testcase{
+		{"reworded description", func(res *AuditResult) { res.Description = "affected by ISTIO-SECURITY-2022-005" }, true},
+		{"other references", func(res *AuditResult) { res.References = []string{"https://istio.io/news/"} }, true},
+		{"other api version", func(res *AuditResult) { res.APIVersion = "v1" }, true},
+		{"other id", func(res *AuditResult) { res.ID = "ISTIO-SECURITY-2022-004" }, false},
+		{"other resource", func(res *AuditResult) { res.Resource = "Version 1.14.2" }, false},
+		{"other cluster", func(res *AuditResult) { res.Cluster = "east" }, false},
+		{"other path", func(res *AuditResult) { res.Path = "spec.servers[1].hosts[0]" }, true},
+	}
+	for _, tc := range testcases {
+		res := base
+		tc.change(&res)
+		assert.Equal(t, tc.same, Fingerprint(res) == Fingerprint(base), tc.description)
+	}
+}
+
+const istioKindsYAML = `apiVersion: networking.istio.io/v1alpha3
+kind: Sidecar
+metadata:
+  name: default
+  namespace: apps
+spec:
+  egress:
+  - hosts: ["./*"]
+---
+apiVersion: security.istio.io/v1beta1
+kind: RequestAuthentication
+metadata:
+  name: jwt
+  namespace: apps
+spec:
+  jwtRules:
+  - issuer: https://example.com
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: WorkloadEntry
+metadata:
+  name: vm
+  namespace: apps
+spec:
+  address: 10.0.0.1
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: WorkloadGroup
+metadata:
+  name: vms
+  namespace: apps
+spec:
+  template:
+    serviceAccount: vm
+---
+apiVersion: telemetry.istio.io/v1alpha1
+kind: Telemetry
+metadata:
+  name: mesh-default
+  namespace: istio-system
+spec:
+  accessLogging:
+  - providers:
+    - name: envoy
+---
+apiVersion: networking.istio.io/v1beta1
+kind: ProxyConfig
+metadata:
+  name: concurrency
+  namespace: apps
+spec:
+  concurrency: 2
+---
+apiVersion: extensions.istio.io/v1alpha1
+kind: WasmPlugin
+metadata:
+  name: auth
+  namespace: apps
+spec:
+  url: oci://example.com/auth:latest
+`
+
+func TestLoadIstioKinds(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		// the same resources in two files must only be loaded once
+		err := os.WriteFile(filepath.Join(dir, name), []byte(istioKindsYAML), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(r Resources) {
+		assert.Equal(t, 1, len(r.Sidecars))
+		assert.Equal(t, 1, len(r.RequestAuthentications))
+		assert.Equal(t, 1, len(r.WorkloadEntries))
+		assert.Equal(t, 1, len(r.WorkloadGroups))
+		assert.Equal(t, 1, len(r.Telemetries))
+		assert.Equal(t, 1, len(r.ProxyConfigs))
+		assert.Equal(t, 1, len(r.WasmPlugins))
+		assert.Equal(t, "./*", r.Sidecars[0].Spec.Egress[0].Hosts[0])
+		assert.Equal(t, "oci://example.com/auth:latest", r.WasmPlugins[0].Spec.Url)
+	}
+
+	resources := NewResources()
+	if err := resources.LoadFromDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	check(resources)
+
+	exported := t.TempDir()
+	if err := resources.Export(exported); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewResources()
+	if err := reloaded.LoadFromDirectory(exported); err != nil {
+		t.Fatal(err)
+	}
+	check(reloaded)
+	assert.Equal(t, resources.Len(), reloaded.Len())
+}
+
+const coreKindsYAML = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: httpbin
+  namespace: apps
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+  namespace: apps
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{"data":{"token":"c2VjcmV0"}}'
+type: Opaque
+data:
+  token: c2VjcmV0
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: apps
+  labels:
+    istio-injection: enabled
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: httpbin
+  namespace: apps
+spec:
+  selector:
+    matchLabels:
+      app: httpbin
+  template:
+    metadata:
+      labels:
+        app: httpbin
+    spec:
+      serviceAccountName: httpbin
+      containers:
+      - name: httpbin
+        image: kennethreitz/httpbin
+---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: deny-all
+  namespace: apps
+spec:
+  podSelector: {}
+`
+
+func TestLoadCoreKinds(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "core.yaml"), []byte(coreKindsYAML), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resources := NewResources()
+	if err := resources.LoadFromDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 1, len(resources.ServiceAccounts))
+	assert.Equal(t, 1, len(resources.Deployments))
+	assert.Equal(t, 1, len(resources.NetworkPolicies))
+
+	// the namespace synthesized for the service account is replaced
+	assert.Equal(t, 1, len(resources.Namespaces))
+	assert.Equal(t, "enabled", resources.Namespaces[0].Labels["istio-injection"])
+
+	assert.Equal(t, 1, len(resources.Secrets))
+	secret := resources.Secrets[0]
+	assert.Equal(t, "token", secret.Name)
+	assert.Equal(t, 0, len(secret.Data))
+	assert.Equal(t, 0, len(secret.Annotations))
+
+	exported := t.TempDir()
+	if err := resources.Export(exported); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(exported, "secrets.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, false, bytes.Contains(data, []byte("c2VjcmV0")))
+}
+
+const gatewayAPIYAML = `apiVersion: gateway.networking.k8s.io/v1alpha2
+kind: Gateway
+metadata:
+  name: ingress
+  namespace: apps
+spec:
+  gatewayClassName: istio
+  listeners:
+  - name: http
+    port: 80
+    protocol: HTTP
+---
+apiVersion: gateway.networking.k8s.io/v1alpha2
+kind: GRPCRoute
+metadata:
+  name: grpc
+  namespace: apps
+spec:
+  parentRefs:
+  - name: ingress
+---
+apiVersion: gateway.networking.k8s.io/v1beta1
+kind: ReferenceGrant
+metadata:
+  name: certs
+  namespace: apps
+spec:
+  from:
+  - group: gateway.networking.k8s.io
+    kind: Gateway
+    namespace: istio-ingress
+  to:
+  - group: ""
+    kind: Secret
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: ingress
+  namespace: apps
+spec:
+  servers:
+  - hosts: ["*"]
+    port:
+      number: 80
+      name: http
+      protocol: HTTP
+`
+
+func TestLoadGatewayAPI(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "gateways.yaml"), []byte(gatewayAPIYAML), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(r Resources) {
+		assert.Equal(t, 1, len(r.KubernetesGateways))
+		assert.Equal(t, 1, len(r.GRPCRoutes))
+		assert.Equal(t, 1, len(r.ReferenceGrants))
+		assert.Equal(t, 1, len(r.Gateways))
+
+		// gateways of the same name are told apart by group
+		k8s := r.Lookup("gateway.networking.k8s.io/v1beta1", "Gateway", "apps:ingress")
+		istio := r.Lookup("networking.istio.io/v1alpha3", "Gateway", "apps:ingress")
+		assert.NotEqual(t, nil, k8s)
+		assert.NotEqual(t, nil, istio)
+		assert.NotEqual(t, k8s, istio)
+	}
+
+	resources := NewResources()
+	if err := resources.LoadFromDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	check(resources)
+
+	exported := t.TempDir()
+	if err := resources.Export(exported); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewResources()
+	if err := reloaded.LoadFromDirectory(exported); err != nil {
+		t.Fatal(err)
+	}
+	check(reloaded)
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	securityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
+	telemetryv1alpha1 "istio.io/client-go/pkg/apis/telemetry/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientsetscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// Istio serves each kind in several versions with the same schema. Every
+// version of a kind is loaded into one internal type, the type of the field
+// of Resources that holds the kind, while the apiVersion the object was
+// written in is kept in its TypeMeta so that it is exported unchanged.
+//
+// Versions that client-go defines Go types for, such as networking v1beta1,
+// are converted to the internal type in Load. Versions that it does not
+// define, listed in servedVersions, decode directly into the internal type.
+
+// servedVersions are the versions served by newer Istio releases that have no
+// Go types in client-go, along with the internal types of their kinds.
+var servedVersions = []struct {
+	gv    schema.GroupVersion
+	types []runtime.Object
+}{
+	{
+		gv: schema.GroupVersion{Group: networkingv1alpha3.GroupName, Version: "v1"},
+		types: []runtime.Object{
+			&networkingv1alpha3.DestinationRule{}, &networkingv1alpha3.DestinationRuleList{},
+			&networkingv1alpha3.Gateway{}, &networkingv1alpha3.GatewayList{},
+			&networkingv1alpha3.ServiceEntry{}, &networkingv1alpha3.ServiceEntryList{},
+			&networkingv1alpha3.Sidecar{}, &networkingv1alpha3.SidecarList{},
+			&networkingv1alpha3.VirtualService{}, &networkingv1alpha3.VirtualServiceList{},
+			&networkingv1alpha3.WorkloadEntry{}, &networkingv1alpha3.WorkloadEntryList{},
+			&networkingv1alpha3.WorkloadGroup{}, &networkingv1alpha3.WorkloadGroupList{},
+		},
+	},
+	{
+		gv: schema.GroupVersion{Group: securityv1beta1.GroupName, Version: "v1"},
+		types: []runtime.Object{
+			&securityv1beta1.AuthorizationPolicy{}, &securityv1beta1.AuthorizationPolicyList{},
+			&securityv1beta1.PeerAuthentication{}, &securityv1beta1.PeerAuthenticationList{},
+			&securityv1beta1.RequestAuthentication{}, &securityv1beta1.RequestAuthenticationList{},
+		},
+	},
+	{
+		gv: schema.GroupVersion{Group: telemetryv1alpha1.GroupName, Version: "v1"},
+		types: []runtime.Object{
+			&telemetryv1alpha1.Telemetry{}, &telemetryv1alpha1.TelemetryList{},
+		},
+	},
+}
+
+// loadConverted loads obj as the internal type out, keeping the apiVersion
+// obj was written in. The specs of both types share one schema, so the
+// conversion goes through their JSON representation.
+func (r *Resources) loadConverted(obj, out runtime.Object) {
+	gvks, _, err := clientsetscheme.Scheme.ObjectKinds(obj)
+	if err == nil {
+		var data []byte
+		data, err = json.Marshal(obj)
+		if err == nil {
+			err = json.Unmarshal(data, out)
+		}
+	}
+	if err != nil {
+		log.WithFields(log.Fields{
+			"type": obj.GetObjectKind().GroupVersionKind().String(),
+			"err":  err,
+		}).Warn("failed to convert resource")
+		return
+	}
+
+	out.GetObjectKind().SetGroupVersionKind(gvks[0])
+	r.Load([]runtime.Object{out})
+}
@@ -0,0 +1,151 @@
+// Copyright 2021 Praetorian Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	networkingv1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
+)
+
+const istioVersionsYAML = `apiVersion: networking.istio.io/v1beta1
+kind: Gateway
+metadata:
+  name: beta
+  namespace: apps
+spec:
+  servers:
+  - hosts: ["*"]
+    port: {number: 80, name: http, protocol: HTTP}
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: alpha
+  namespace: apps
+spec:
+  servers:
+  - hosts: ["httpbin.example.com"]
+    port: {number: 80, name: http, protocol: HTTP}
+---
+apiVersion: networking.istio.io/v1
+kind: VirtualService
+metadata:
+  name: httpbin
+  namespace: apps
+spec:
+  hosts: ["httpbin.example.com"]
+  gateways: ["beta"]
+  http:
+  - route:
+    - destination: {host: httpbin}
+---
+apiVersion: networking.istio.io/v1beta1
+kind: DestinationRule
+metadata:
+  name: httpbin
+  namespace: apps
+spec:
+  host: httpbin
+  trafficPolicy:
+    tls: {mode: SIMPLE}
+---
+apiVersion: security.istio.io/v1
+kind: AuthorizationPolicy
+metadata:
+  name: deny-admin
+  namespace: apps
+spec:
+  action: DENY
+  rules:
+  - to:
+    - operation:
+        paths: ["/admin"]
+---
+apiVersion: security.istio.io/v1
+kind: PeerAuthentication
+metadata:
+  name: default
+  namespace: istio-system
+spec:
+  mtls: {mode: STRICT}
+---
+apiVersion: telemetry.istio.io/v1
+kind: Telemetry
+metadata:
+  name: mesh-default
+  namespace: istio-system
+spec:
+  accessLogging:
+  - providers:
+    - name: envoy
+`
+
+func TestLoadIstioVersions(t *testing.T) {
+	check := func(r Resources) {
+		assert.Equal(t, 2, len(r.Gateways))
+		assert.Equal(t, 1, len(r.VirtualServices))
+		assert.Equal(t, 1, len(r.DestinationRules))
+		assert.Equal(t, 1, len(r.AuthorizationPolicies))
+		assert.Equal(t, 1, len(r.PeerAuthentications))
+		assert.Equal(t, 1, len(r.Telemetries))
+
+		versions := make(map[string]string)
+		for _, gw := range r.Gateways {
+			versions[gw.Name] = gw.APIVersion
+		}
+		assert.Equal(t, "networking.istio.io/v1beta1", versions["beta"])
+		assert.Equal(t, "networking.istio.io/v1alpha3", versions["alpha"])
+		assert.Equal(t, "networking.istio.io/v1", r.VirtualServices[0].APIVersion)
+		assert.Equal(t, "networking.istio.io/v1beta1", r.DestinationRules[0].APIVersion)
+		assert.Equal(t, "security.istio.io/v1", r.AuthorizationPolicies[0].APIVersion)
+
+		assert.Equal(t, "*", r.Gateways[0].Spec.Servers[0].Hosts[0])
+		assert.Equal(t, "beta", r.VirtualServices[0].Spec.Gateways[0])
+		assert.Equal(t, "/admin", r.AuthorizationPolicies[0].Spec.Rules[0].To[0].Operation.Paths[0])
+		assert.Equal(t, "STRICT", r.PeerAuthentications[0].Spec.Mtls.Mode.String())
+
+		_, ok := r.Lookup("networking.istio.io/v1beta1", "Gateway", "apps:beta").(*networkingv1alpha3.Gateway)
+		assert.T(t, ok)
+	}
+
+	resources := NewResources()
+	err := resources.LoadFromReader("istio.yaml", strings.NewReader(istioVersionsYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(resources)
+
+	exported := t.TempDir()
+	if err := resources.Export(exported); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(exported, "authorizationpolicies.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.T(t, strings.Contains(string(data), "apiVersion: security.istio.io/v1\n"))
+
+	reloaded := NewResources()
+	if err := reloaded.LoadFromDirectory(exported); err != nil {
+		t.Fatal(err)
+	}
+	check(reloaded)
+	assert.Equal(t, resources.Len(), reloaded.Len())
+}
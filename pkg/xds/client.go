@@ -28,9 +28,9 @@ import (
 	"github.com/gogo/protobuf/proto"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	mcp "istio.io/api/mcp/v1alpha1"
 	istioscheme "istio.io/client-go/pkg/clientset/versioned/scheme"
-	"istio.io/pkg/version"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -39,33 +39,84 @@ import (
 	blockinggrpc "github.com/praetorian-inc/snowcat/pkg/grpc"
 )
 
+// DefaultTimeout bounds connecting and each request when Options do not set
+// a timeout.
+const DefaultTimeout = 5 * time.Second
+
+// Options configure a Client.
+type Options struct {
+	// Timeout bounds connecting and each request. It defaults to
+	// DefaultTimeout.
+	Timeout time.Duration
+	// Credentials, when set, make the client connect to istiod's secure xds
+	// over TLS rather than in plaintext.
+	Credentials *Credentials
+	// ServerName is the name that istiod's certificate is verified against
+	// when it is dialed by IP address, see ServiceName. Connecting to the
+	// secure xds of an IP address fails without it.
+	ServerName string
+}
+
+// Port returns the port of istiod's xds that a client with the options
+// connects to.
+func (o Options) Port() string {
+	if o.Credentials != nil {
+		return SecurePort
+	}
+	return PlaintextPort
+}
+
 // Client wraps Envoy XDS and exposes methods to query data.
 type Client struct {
 	discoveryAddr string
 	opts          []grpc.DialOption
+	creds         credentials.TransportCredentials
+	timeout       time.Duration
+	namespace     string
 
 	conn   *grpc.ClientConn
 	connMu sync.Mutex
 
-	stream discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+	stream       discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+	cancelStream context.CancelFunc
 
 	decoder runtime.Decoder
 }
 
 // NewClient creates an XDS client given a GRPC address.
-func NewClient(addr string) (*Client, error) {
+func NewClient(ctx context.Context, addr string, opts Options) (*Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
 	cli := &Client{
 		discoveryAddr: addr,
 		opts: []grpc.DialOption{
 			grpc.WithInsecure(),
 		},
+		timeout: opts.Timeout,
 		decoder: clientsetscheme.Codecs.UniversalDeserializer(),
 	}
-	_, err := cli.Version(context.Background())
+	if creds := opts.Credentials; creds != nil {
+		transport, err := creds.transport(addr, opts.ServerName)
+		if err != nil {
+			return nil, err
+		}
+		cli.creds = transport
+		cli.opts = []grpc.DialOption{
+			grpc.WithPerRPCCredentials(creds),
+		}
+		cli.namespace = creds.Namespace
+	}
+	_, err := cli.Version(ctx)
 	return cli, err
 }
 
 func (xds *Client) makeNodeID() string {
+	if xds.namespace != "" {
+		// istiod rejects authenticated proxies that claim another namespace
+		// than the one of their service account
+		return fmt.Sprintf("sidecar~0.0.0.0~snowcat.%s~%s.svc.cluster.local", xds.namespace, xds.namespace)
+	}
 	// TODO: should we attempt to populate this?
 	return "sidecar~0.0.0.0~mithril~mithril"
 }
@@ -85,24 +136,30 @@ func (xds *Client) connect(ctx context.Context) error {
 
 	var err error
 
-	connctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	connctx, cancel := context.WithTimeout(ctx, xds.timeout)
 	defer cancel()
 
 	log.WithFields(log.Fields{
 		"addr": xds.discoveryAddr,
 	}).Debug("connecting to xds")
 
-	xds.conn, err = blockinggrpc.BlockingDial(connctx, "tcp", xds.discoveryAddr, nil, xds.opts...)
+	xds.conn, err = blockinggrpc.BlockingDial(connctx, "tcp", xds.discoveryAddr, xds.creds, xds.opts...)
 	if err != nil {
 		return err
 	}
 
+	// The stream outlives any single request, so it is bound to the client
+	// rather than to ctx and is torn down by Close.
+	streamctx, cancelStream := context.WithCancel(context.Background())
 	xds.stream, err = discovery.NewAggregatedDiscoveryServiceClient(xds.conn).
-		StreamAggregatedResources(ctx)
+		StreamAggregatedResources(streamctx)
 	if err != nil {
-		xds.Close()
+		cancelStream()
+		xds.conn.Close()
+		xds.conn = nil
 		return err
 	}
+	xds.cancelStream = cancelStream
 
 	return nil
 }
@@ -113,9 +170,13 @@ func (xds *Client) Close() error {
 	defer xds.connMu.Unlock()
 
 	if xds.conn != nil {
+		if xds.cancelStream != nil {
+			xds.cancelStream()
+		}
 		err := xds.conn.Close()
 		xds.conn = nil
 		xds.stream = nil
+		xds.cancelStream = nil
 		return err
 	}
 	return nil
@@ -140,11 +201,43 @@ func (xds *Client) send(ctx context.Context, req *discovery.DiscoveryRequest) (*
 		}).Trace("sending xds request")
 	}
 
-	err := xds.stream.Send(req)
-	if err != nil {
-		return nil, err
+	ctx, cancel := context.WithTimeout(ctx, xds.timeout)
+	defer cancel()
+
+	type result struct {
+		resp *discovery.DiscoveryResponse
+		err  error
+	}
+	stream := xds.stream
+	done := make(chan result, 1)
+	go func() {
+		if err := stream.Send(req); err != nil {
+			done <- result{err: err}
+			return
+		}
+		resp, err := stream.Recv()
+		done <- result{resp: resp, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.resp, res.err
+	case <-ctx.Done():
+		// The stream may be left mid-request, so drop the connection and let
+		// the next request reconnect.
+		xds.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// serverInfo is the control plane identifier reported by istiod, a subset of
+// istio.io/pkg/version.ServerInfo.
+type serverInfo struct {
+	Component string
+	ID        string
+	Info      struct {
+		Version string `json:"version"`
 	}
-	return xds.stream.Recv()
 }
 
 // Version queries the XDS server and retrieves its version.
@@ -168,7 +261,7 @@ func (xds *Client) Version(ctx context.Context) (string, error) {
 
 	ident := res.ControlPlane.Identifier
 
-	var info version.ServerInfo
+	var info serverInfo
 	err = json.Unmarshal([]byte(ident), &info)
 	if err != nil {
 		return "", err
@@ -0,0 +1,181 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc/credentials"
+
+	"github.com/praetorian-inc/snowcat/pkg/types"
+)
+
+// Ports of istiod's xds.
+const (
+	PlaintextPort = "15010"
+	SecurePort    = "15012"
+)
+
+// Paths of the credentials that are mounted into a sidecar.
+const (
+	// DefaultRootCertPath holds the root certificate of the mesh.
+	DefaultRootCertPath = "/var/run/secrets/istio/root-cert.pem"
+	// IstioTokenPath holds the service account token projected for istiod.
+	IstioTokenPath = "/var/run/secrets/tokens/istio-token"
+	// ServiceAccountTokenPath holds the default service account token.
+	ServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// Credentials authenticate a client to istiod's secure xds the way a sidecar
+// does: the server certificate is verified against the root certificate of
+// the mesh, and the workload's service account token is sent with each
+// request.
+type Credentials struct {
+	// RootCAs holds the root certificate of the mesh.
+	RootCAs *x509.CertPool
+	// Token is the service account JWT of the workload.
+	Token string
+	// Namespace is the namespace of the service account, which istiod
+	// requires the proxy to claim.
+	Namespace string
+}
+
+// LoadCredentials reads the root certificate and the token. When tokenPath is
+// empty, the istio token is used if mounted, and the service account token
+// otherwise.
+func LoadCredentials(rootCertPath, tokenPath string) (*Credentials, error) {
+	pem, err := os.ReadFile(rootCertPath)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", rootCertPath)
+	}
+
+	paths := []string{tokenPath}
+	if tokenPath == "" {
+		paths = []string{IstioTokenPath, ServiceAccountTokenPath}
+	}
+	var token []byte
+	for _, path := range paths {
+		token, err = os.ReadFile(path)
+		if err == nil || !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	creds := &Credentials{
+		RootCAs: pool,
+		Token:   strings.TrimSpace(string(token)),
+	}
+	creds.Namespace = tokenNamespace(creds.Token)
+	return creds, nil
+}
+
+// tokenNamespace returns the namespace of the service account that a token
+// was issued to, or an empty string if the token cannot be read.
+func tokenNamespace(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		// bound tokens, such as the istio token
+		Kubernetes struct {
+			Namespace string `json:"namespace"`
+		} `json:"kubernetes.io"`
+		// legacy service account tokens
+		Namespace string `json:"kubernetes.io/serviceaccount/namespace"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	if claims.Kubernetes.Namespace != "" {
+		return claims.Kubernetes.Namespace
+	}
+	return claims.Namespace
+}
+
+// ServiceName returns the name of the service of a revision's istiod, which
+// its certificate is issued for, e.g. istiod-canary.istio-system.svc.
+func ServiceName(revision, namespace string) string {
+	name := "istiod"
+	if revision != "" && revision != types.DefaultRevision {
+		name += "-" + revision
+	}
+	return name + "." + namespace + ".svc"
+}
+
+// serverName returns the name that the certificate of the server at addr is
+// verified against: the service name of the address, or name when an IP
+// address is dialed. istiod certificates name the service without the
+// cluster domain, e.g. istiod.istio-system.svc.
+func serverName(addr, name string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if net.ParseIP(host) != nil {
+		return name
+	}
+	if i := strings.Index(host, ".svc."); i >= 0 {
+		host = host[:i+len(".svc")]
+	}
+	return host
+}
+
+// transport returns the TLS credentials for dialing addr. The certificate
+// must name the service of istiod, as every workload of the mesh holds a
+// certificate that chains to the root and could otherwise collect the token.
+func (c *Credentials) transport(addr, name string) (credentials.TransportCredentials, error) {
+	name = serverName(addr, name)
+	if name == "" {
+		return nil, fmt.Errorf("no server name to verify the certificate of %s against", addr)
+	}
+	return credentials.NewTLS(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    c.RootCAs,
+		ServerName: name,
+	}), nil
+}
+
+// GetRequestMetadata sends the token as a bearer token, as sidecars do.
+func (c *Credentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Bearer " + c.Token,
+	}, nil
+}
+
+// RequireTransportSecurity prevents the token from being sent in plaintext.
+func (c *Credentials) RequireTransportSecurity() bool {
+	return true
+}
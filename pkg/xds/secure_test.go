@@ -0,0 +1,210 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bmizerany/assert"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeIstiod serves the aggregated discovery service to proxies that present
+// its token, answering each request with its version.
+type fakeIstiod struct {
+	discovery.UnimplementedAggregatedDiscoveryServiceServer
+	token string
+
+	mu    sync.Mutex
+	nodes []string
+}
+
+func (s *fakeIstiod) StreamAggregatedResources(stream discovery.AggregatedDiscoveryService_StreamAggregatedResourcesServer) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if auth := md.Get("authorization"); len(auth) != 1 || auth[0] != "Bearer "+s.token {
+		return status.Error(codes.Unauthenticated, "authentication failure")
+	}
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.nodes = append(s.nodes, req.Node.GetId())
+		s.mu.Unlock()
+
+		err = stream.Send(&discovery.DiscoveryResponse{
+			TypeUrl: req.TypeUrl,
+			ControlPlane: &core.ControlPlane{
+				Identifier: `{"Component":"istiod","ID":"istiod-0","Info":{"version":"1.14.1"}}`,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// newCert returns a certificate for the DNS names signed by parent, or
+// self-signed if parent is nil.
+func newCert(t *testing.T, parent *tls.Certificate, dnsNames ...string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{Organization: []string{"cluster.local"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     dnsNames,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	signer, signerKey := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// serve starts an xds server that presents cert.
+func serve(t *testing.T, cert tls.Certificate, istiod *fakeIstiod) string {
+	srv := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	discovery.RegisterAggregatedDiscoveryServiceServer(srv, istiod)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(lis) // nolint:errcheck
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestSecureClient(t *testing.T) {
+	root := newCert(t, nil)
+	other := newCert(t, nil)
+	serving := newCert(t, &root, "istiod.istio-system.svc")
+	// every workload of the mesh holds a certificate signed by the root
+	sidecar := newCert(t, &root)
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"kubernetes.io":{"namespace":"workload"}}`))
+	token := "eyJhbGciOiJSUzI1NiJ9." + payload + ".c2lnbmF0dXJl"
+
+	istiod := &fakeIstiod{token: token}
+	addr := serve(t, serving, istiod)
+	impostor := serve(t, sidecar, &fakeIstiod{token: token})
+
+	dir := t.TempDir()
+	rootPath := writeFile(t, dir, "root-cert.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Certificate[0]}))
+	otherPath := writeFile(t, dir, "other-cert.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: other.Certificate[0]}))
+	tokenPath := writeFile(t, dir, "istio-token", []byte(token+"\n"))
+	wrongTokenPath := writeFile(t, dir, "wrong-token", []byte("wrong"))
+
+	type testcase struct {
+		description string
+		addr        string
+		rootCert    string
+		token       string
+		serverName  string
+		ok          bool
+	}
+	cases := []testcase{
+		{"ip address without a server name", addr, rootPath, tokenPath, "", false},
+		{"matching server name", addr, rootPath, tokenPath, "istiod.istio-system.svc", true},
+		{"other server name", addr, rootPath, tokenPath, "istiod-canary.istio-system.svc", false},
+		{"workload certificate", impostor, rootPath, tokenPath, "istiod.istio-system.svc", false},
+		{"other root certificate", addr, otherPath, tokenPath, "istiod.istio-system.svc", false},
+		{"wrong token", addr, rootPath, wrongTokenPath, "istiod.istio-system.svc", false},
+	}
+	for _, tc := range cases {
+		creds, err := LoadCredentials(tc.rootCert, tc.token)
+		assert.Equal(t, nil, err, tc.description)
+
+		opts := Options{Timeout: 2 * time.Second, Credentials: creds, ServerName: tc.serverName}
+		cli, err := NewClient(context.Background(), tc.addr, opts)
+		assert.Equal(t, tc.ok, err == nil, tc.description, err)
+		if err != nil {
+			continue
+		}
+		version, err := cli.Version(context.Background())
+		assert.Equal(t, nil, err, tc.description)
+		assert.Equal(t, "1.14.1", version, tc.description)
+		cli.Close()
+	}
+
+	assert.Equal(t, "workload", tokenNamespace(token))
+	istiod.mu.Lock()
+	defer istiod.mu.Unlock()
+	assert.Equal(t, "sidecar~0.0.0.0~snowcat.workload~workload.svc.cluster.local", istiod.nodes[0])
+}
+
+func TestServerName(t *testing.T) {
+	type testcase struct {
+		addr     string
+		fallback string
+		name     string
+	}
+	cases := []testcase{
+		{"istiod.istio-system.svc.cluster.local:15012", ServiceName("canary", "istio-system"), "istiod.istio-system.svc"},
+		{"istiod-canary.istio-system.svc:15012", "", "istiod-canary.istio-system.svc"},
+		{"10.0.0.5:15012", ServiceName("canary", "istio-system"), "istiod-canary.istio-system.svc"},
+		{"10.0.0.5:15012", ServiceName("default", "istio-system"), "istiod.istio-system.svc"},
+		{"10.0.0.5:15012", "", ""},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.name, serverName(tc.addr, tc.fallback), tc.addr)
+	}
+}